@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/hyperstripe50/gopher-jasmine/suite"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ErrorResponse struct {
@@ -14,43 +17,454 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 type Api struct {
-	suites []suite.Suite
+	suites        []suite.Suite
+	problems      []suite.Problem
+	runs          *runManager
+	failureStatus int
+	server        *http.Server
+	prefix        string
+	access        access
+	scheduler     *scheduler
+	health        map[string]HealthThresholds
+	defaultHealth HealthThresholds
+	runOnGet      bool
 }
 
+const (
+	DuplicateEndpointProblem    = "duplicate_endpoint"
+	ReservedEndpointProblem     = "reserved_endpoint"
+	InvalidFailureStatusProblem = "invalid_failure_status"
+)
+
+// defaultFailureStatus is the status of a suite endpoint's response when a
+// spec failed or a hook raised, unless WithFailureStatus says otherwise.
+const defaultFailureStatus = http.StatusInternalServerError
+
+// shutdownGracePeriod is how long Shutdown waits for cancelled runs whose
+// running specs ignore the cancellation.
+const shutdownGracePeriod = time.Second
+
+// reservedEndpoints are served by the Api itself and cannot be used by suites.
+var reservedEndpoints = []string{"plan", "runs", "ws", "dashboard", "all", "history", "schedules", "webhooks", "metrics", "results", "health"}
+
+// NewApi validates the suites and prepares serving them. A misconfigured
+// suite tree does not panic here, but ListenAndServe refuses to start and
+// Problems lists what is wrong.
 func NewApi(suites []suite.Suite) *Api {
-	return &Api{
-		suites: suites,
+	return NewApiWithServer(suites, &http.Server{})
+}
+
+// NewApiWithServer is NewApi serving through server, so that its timeouts,
+// TLS configuration and other settings apply. ListenAndServe replaces the
+// Handler of server.
+func NewApiWithServer(suites []suite.Suite, server *http.Server) *Api {
+	api := &Api{
+		suites:        suites,
+		runs:          newRunManager(),
+		failureStatus: defaultFailureStatus,
+		server:        server,
+		scheduler:     newScheduler(),
+		health:        make(map[string]HealthThresholds),
+	}
+	for _, s := range suites {
+		api.runs.metrics.register(s.GetName())
+	}
+	api.problems = validateSuites(suites)
+	for _, problem := range api.problems {
+		fmt.Printf("INVALID Suite: %s\n", problem)
+	}
+	return api
+}
+
+// WithRunRetention sets how many finished runs started through the runs
+// endpoint are kept for retrieval.
+func (api *Api) WithRunRetention(count int) *Api {
+	api.runs.retain = count
+	return api
+}
+
+// WithFailureStatus sets the status a suite endpoint responds with when a
+// spec failed or a hook raised. Runs that succeeded are answered with 200. A
+// status outside 400-599 is a problem and leaves the failure status as it is.
+func (api *Api) WithFailureStatus(status int) *Api {
+	if status < 400 || status > 599 {
+		api.problems = append(api.problems, suite.Problem{
+			Kind:    InvalidFailureStatusProblem,
+			Message: fmt.Sprintf("failure status %d is not a 4xx or 5xx status", status),
+		})
+		fmt.Printf("INVALID Suite: %s\n", api.problems[len(api.problems)-1])
+		return api
+	}
+	api.failureStatus = status
+	return api
+}
+
+// WithPrefix serves every endpoint below prefix, e.g. "/tests", for mounting
+// the Handler into an existing router without stripping the prefix.
+func (api *Api) WithPrefix(prefix string) *Api {
+	api.prefix = strings.TrimSuffix(prefix, "/")
+	return api
+}
+
+// WithRunOnGet restores the old behaviour of running suites on GET. Without
+// it, suites only run on POST: GET on a suite endpoint responds with the
+// result of its last run, and GET on the endpoints of nested suites and specs
+// and on /all is refused with 405.
+func (api *Api) WithRunOnGet(enabled bool) *Api {
+	api.runOnGet = enabled
+	return api
+}
+
+// Problems returns the problems found by validating the suites of the Api.
+func (api *Api) Problems() []suite.Problem {
+	return api.problems
+}
+
+// Handler returns the handler serving the suites, for embedding them into an
+// existing service, and starts the schedules. While the Api has Problems, the
+// schedules do not start and every request is answered with 500 and the
+// problems, like ListenAndServe refuses to start.
+func (api *Api) Handler() http.Handler {
+	if len(api.problems) == 0 {
+		api.scheduler.start(api.runs)
+	}
+	router := api.router()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(api.problems) > 0 {
+			writeError(w, http.StatusInternalServerError, (&suite.ValidationError{Problems: api.problems}).Error())
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// ListenAndServe serves the Api on port, or on the Addr of the server passed
+// to NewApiWithServer if port is empty. The server uses TLS if its TLSConfig
+// has certificates. It returns nil once Shutdown stopped the server.
+func (api *Api) ListenAndServe(port string) error {
+	if len(api.problems) > 0 {
+		return &suite.ValidationError{Problems: api.problems}
+	}
+	if port != "" {
+		api.server.Addr = port
 	}
+	api.server.Handler = api.Handler()
+	fmt.Printf("starting server on port%s\n", api.server.Addr)
+	var err error
+	if config := api.server.TLSConfig; config != nil && (len(config.Certificates) > 0 || config.GetCertificate != nil) {
+		err = api.server.ListenAndServeTLS("", "")
+	} else {
+		err = api.server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
 }
-func (api *Api) ListenAndServe(port string) {
-	r := mux.NewRouter()
-	endpoints := make([]string, 0)
+
+// Shutdown stops the schedules, stops accepting runs and connections and
+// waits for the runs in flight to finish. Once ctx is done, the remaining runs
+// are cancelled: specs that already started finish, all others are reported
+// as SKIPPED. If the cancelled runs do not finish within a short grace period,
+// the server is shut down without waiting for them and ctx.Err() is returned.
+// Otherwise it waits for pending webhook deliveries until ctx is done and
+// shuts the server down, see http.Server.Shutdown.
+func (api *Api) Shutdown(ctx context.Context) error {
+	api.scheduler.stop()
+	done := api.runs.close()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		api.runs.cancelAll()
+		select {
+		case <-done:
+		case <-time.After(shutdownGracePeriod):
+			api.server.Shutdown(ctx)
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-api.runs.webhooks.drained():
+	case <-ctx.Done():
+	}
+	return api.server.Shutdown(ctx)
+}
+func (api *Api) router() *mux.Router {
+	root := mux.NewRouter()
+	root.Use(api.authenticate)
+	r := root
+	if api.prefix != "" {
+		r = root.PathPrefix(api.prefix).Subrouter()
+		r.HandleFunc("", createIndexHandler(api))
+	}
 	for _, s := range api.suites {
-		name := strings.ToLower(s.GetName())
-		name = strings.Join(strings.Split(name, " "), "-")
-		endpoints = append(endpoints, name)
-		r.HandleFunc(fmt.Sprintf("/%s", name), createSuiteHandler(s))
-	}
-	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		j, _ := json.Marshal(endpoints)
-		fmt.Fprintf(w, string(j))
+		name := slugify(s.GetName())
+		r.HandleFunc(fmt.Sprintf("/%s", name), createSuiteHandler(api, s)).Methods(http.MethodGet, http.MethodHead, http.MethodPost)
+		r.HandleFunc(fmt.Sprintf("/%s/{path:.+}", name), createNestedSuiteHandler(api, s)).Methods(http.MethodGet, http.MethodPost)
+	}
+	r.HandleFunc("/all", createRunAllHandler(api)).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/plan", createPlanHandler(api))
+	r.HandleFunc("/plan/{suite}", createPlanHandler(api))
+	r.HandleFunc("/runs", createStartRunHandler(api)).Methods(http.MethodPost)
+	r.HandleFunc("/runs", createListRunsHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/runs/{id}", createGetRunHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/runs/{id}", createCancelRunHandler(api)).Methods(http.MethodDelete)
+	r.HandleFunc("/runs/{id}/events", createRunEventsHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/history", createListHistoryHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/history/{id}", createGetHistoryHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/schedules", createListSchedulesHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/deliveries", createListDeliveriesHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/metrics", createMetricsHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/results/{suite}", createLastResultHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/health", createHealthHandler(api)).Methods(http.MethodGet)
+	r.HandleFunc("/health/ready", createReadinessHandler(api)).Methods(http.MethodGet).Name("readiness probe")
+	r.HandleFunc("/health/live", createLivenessHandler()).Methods(http.MethodGet).Name("liveness probe")
+	r.HandleFunc("/ws", createWebSocketHandler(api))
+	r.HandleFunc("/dashboard", createDashboardHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/", createIndexHandler(api))
+	root.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No endpoint at '%s'", r.URL.Path))
 	})
-	fmt.Printf("starting server on port%s\n", port)
-	http.ListenAndServe(port, r)
+	root.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s is not allowed at '%s'", r.Method, r.URL.Path))
+	})
+	return root
 }
 
-func createSuiteHandler(suite suite.Suite) func(w http.ResponseWriter, r *http.Request) {
+// createIndexHandler lists the endpoints of the suites the sender may list.
+func createIndexHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("%s\n", suite.GetName())
-		result := suite.Run()
-		j, err := json.Marshal(result)
-		if err != nil {
-			errorResponse, _ := json.Marshal(ErrorResponse{
-				Status:  "500",
-				Message: fmt.Sprintf("Failed to get results with error: %s", err.Error()),
+		endpoints := make([]string, 0)
+		for _, s := range api.listable(r) {
+			endpoints = append(endpoints, slugify(s.GetName()))
+		}
+		writeJSON(w, http.StatusOK, endpoints)
+	}
+}
+
+// listable returns the top-level suites the sender of r may list.
+func (api *Api) listable(r *http.Request) []suite.Suite {
+	suites := make([]suite.Suite, 0)
+	for _, s := range api.suites {
+		if api.allowed(r, ListAction, slugify(s.GetName())) {
+			suites = append(suites, s)
+		}
+	}
+	return suites
+}
+
+// findSuite returns the top-level suite served at the endpoint name.
+func (api *Api) findSuite(name string) (suite.Suite, bool) {
+	for _, s := range api.suites {
+		if slugify(s.GetName()) == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// slugify turns a suite name into the endpoint it is served at.
+func slugify(name string) string {
+	name = strings.ToLower(name)
+	return strings.Join(strings.Split(name, " "), "-")
+}
+
+// validateSuites validates every suite tree and reports top-level suites that
+// would be served at the same endpoint.
+func validateSuites(suites []suite.Suite) []suite.Problem {
+	problems := make([]suite.Problem, 0)
+	endpoints := make(map[string]string)
+	for _, s := range suites {
+		problems = append(problems, suite.Validate(s)...)
+		endpoint := slugify(s.GetName())
+		if other, ok := endpoints[endpoint]; ok {
+			problems = append(problems, suite.Problem{
+				Kind:    DuplicateEndpointProblem,
+				Path:    s.GetName(),
+				Message: fmt.Sprintf("suites '%s' and '%s' are both served at '/%s'", other, s.GetName(), endpoint),
 			})
-			fmt.Fprintf(w, string(errorResponse))
 		}
-		fmt.Fprintf(w, string(j))
+		endpoints[endpoint] = s.GetName()
+		for _, reserved := range reservedEndpoints {
+			if endpoint == reserved {
+				problems = append(problems, suite.Problem{
+					Kind:    ReservedEndpointProblem,
+					Path:    s.GetName(),
+					Message: fmt.Sprintf("suite '%s' would be served at '/%s' which is reserved", s.GetName(), endpoint),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// createSuiteHandler runs s on POST and responds to GET and HEAD with the
+// result of its last run, see WithRunOnGet.
+func createSuiteHandler(api *Api, s suite.Suite) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.runsOn(r) {
+			api.runSuite(w, r, s, r.URL.Query()["select"])
+			return
+		}
+		if !api.authorize(r, ListAction, slugify(s.GetName())) {
+			forbidden(w, ListAction, slugify(s.GetName()))
+			return
+		}
+		api.writeLastResult(w, r, s)
+	}
+}
+
+// runSuite runs the specs of s selected by selection, all if it is empty, and
+// responds with the result in the format requested by r.
+func (api *Api) runSuite(w http.ResponseWriter, r *http.Request, s suite.Suite, selection []string) {
+	format, err := resultFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !api.authorize(r, RunAction, slugify(s.GetName())) {
+		forbidden(w, RunAction, slugify(s.GetName()))
+		return
+	}
+	parameters, err := requestParameters(r, s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fmt.Printf("%s\n", s.GetName())
+	result, err := api.runs.runNow(s, suite.RunOptions{Select: selection, Parameters: parameters}, EndpointTrigger)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	body, contentType, err := serialise(result, format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get results with error: %s", err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(api.resultStatus(result))
+	w.Write(body)
+}
+
+// writeLastResult responds with the result of the last recorded run of s, or
+// 404 if there is none. The result of a run never changes, so the response
+// carries an ETag and a Last-Modified header and conditional requests for the
+// same run are answered with 304.
+func (api *Api) writeLastResult(w http.ResponseWriter, r *http.Request, s suite.Suite) {
+	format, err := resultFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	last, err := lastCompleteRun(api.runs.history, s.GetName())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read history with error: %s", err.Error()))
+		return
+	}
+	if last == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Suite '%s' has not run yet, POST to '/%s' to run it", s.GetName(), slugify(s.GetName())))
+		return
+	}
+	entry := *last
+	etag := fmt.Sprintf(`"%s-%s"`, entry.ID, format)
+	lastModified := entry.FinishedAt.UTC().Truncate(time.Second)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set(RunHeader, entry.ID)
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	body, contentType, err := serialise(entry.Result, format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get results with error: %s", err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(api.resultStatus(entry.Result))
+	w.Write(body)
+}
+
+// notModified reports whether the conditional headers of r are satisfied by
+// a response with etag and lastModified. If-None-Match takes precedence over
+// If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
 	}
-}
\ No newline at end of file
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !lastModified.After(since)
+}
+
+// runsOn reports whether r asks to run suites: POST always does, GET and HEAD
+// only if WithRunOnGet restored the old behaviour.
+func (api *Api) runsOn(r *http.Request) bool {
+	return r.Method == http.MethodPost || api.runOnGet
+}
+
+// runRequiresPost answers a GET to an endpoint that only runs suites.
+func runRequiresPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", http.MethodPost)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s does not run suites at '%s', use POST", r.Method, r.URL.Path))
+}
+
+// resultStatus is the status of a response carrying result.
+func (api *Api) resultStatus(result suite.Result) int {
+	if result.Succeeded() {
+		return http.StatusOK
+	}
+	return api.failureStatus
+}
+
+// createPlanHandler lists the structure of every suite the sender may list,
+// or of the suite named by the path, without running anything.
+func createPlanHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, filtered := mux.Vars(r)["suite"]
+		if filtered && !api.authorize(r, ListAction, name) {
+			forbidden(w, ListAction, name)
+			return
+		}
+		plans := make([]suite.Plan, 0)
+		for _, s := range api.listable(r) {
+			if !filtered || slugify(s.GetName()) == name {
+				plans = append(plans, suite.DryRun(s))
+			}
+		}
+		if !filtered {
+			writeJSON(w, http.StatusOK, plans)
+		} else if len(plans) == 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No suite is served at '/%s'", name))
+		} else {
+			writeJSON(w, http.StatusOK, plans[0])
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	j, err := json.Marshal(body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to marshal response with error: %s", err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, string(j))
+}
+func writeError(w http.ResponseWriter, status int, message string) {
+	j, _ := json.Marshal(ErrorResponse{
+		Status:  strconv.Itoa(status),
+		Message: message,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, string(j))
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewApiRejectsSuitesServedAtTheSameEndpoint(t *testing.T) {
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("parent suite").It("returns 200", noop),
+		suite.NewSequentialSuite("Parent Suite").It("returns 200", noop),
+	})
+
+	problems := api.Problems()
+	if len(problems) != 1 || problems[0].Kind != DuplicateEndpointProblem {
+		t.Fatalf("expected 1 duplicate endpoint problem but got %v", problems)
+	}
+	if err := api.ListenAndServe(":0"); err == nil {
+		t.Errorf("expected server with invalid suites to refuse to start")
+	}
+}
+func TestPlanHandlerListsSuiteWithoutRunningIt(t *testing.T) {
+	ran := false
+	s := suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			ran = true
+			return nil
+		})
+	router := mux.NewRouter()
+	router.HandleFunc("/plan/{suite}", createPlanHandler(NewApi([]suite.Suite{s})))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/plan/parent-suite", nil))
+	var plan suite.Plan
+	if err := json.Unmarshal(recorder.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("expected plan json but got %s", recorder.Body.String())
+	}
+	if ran {
+		t.Errorf("expected plan not to run the suite")
+	}
+	if len(plan.Specs) != 1 || plan.Specs[0].Path != "parent suite > returns 200" {
+		t.Errorf("expected one spec in plan but got %v", plan.Specs)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/plan/unknown-suite", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown suite but got %d", recorder.Code)
+	}
+}
+func TestDashboardIsServedWithoutExternalAssets(t *testing.T) {
+	api := NewApi([]suite.Suite{})
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected html dashboard but got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if strings.Contains(recorder.Body.String(), "src=\"http") || strings.Contains(recorder.Body.String(), "href=\"http") {
+		t.Errorf("expected dashboard not to load external assets")
+	}
+}
+func TestSuiteEndpointServesJUnitOnRequest(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/parent-suite", nil)
+	request.Header.Set("Accept", "application/xml")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Header().Get("Content-Type") != "application/xml" || !strings.Contains(recorder.Body.String(), "<testsuites") {
+		t.Errorf("expected junit xml but got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/parent-suite?format=unknown", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown format but got %d", recorder.Code)
+	}
+}
+
+func TestResultFormatHonoursQValues(t *testing.T) {
+	expectations := map[string]string{
+		"":                                     JSONFormat,
+		"*/*":                                  JSONFormat,
+		"application/xml":                      JUnitFormat,
+		"text/plain;q=0.5, text/x-tap":         TAPFormat,
+		"application/xml;q=0, text/plain;q=.2": TextFormat,
+		"application/xml, */*":                 JSONFormat,
+		"text/x-tap, application/json":         JSONFormat,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": JSONFormat,
+	}
+	for accept, expected := range expectations {
+		request := httptest.NewRequest(http.MethodGet, "/parent-suite", nil)
+		request.Header.Set("Accept", accept)
+		if format, err := resultFormat(request); err != nil || format != expected {
+			t.Errorf("expected %s for Accept '%s' but got %s", expected, accept, format)
+		}
+	}
+}
+
+func TestSuiteEndpointServesPlainText(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/parent-suite", nil)
+	request.Header.Set("Accept", "text/plain")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Header().Get("Content-Type") != "text/plain; charset=utf-8" || !strings.HasPrefix(recorder.Body.String(), "parent suite\n  ✓ returns 200\n") {
+		t.Errorf("expected text report but got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/parent-suite?format=dots", nil))
+	if !strings.HasPrefix(recorder.Body.String(), ".\n") {
+		t.Errorf("expected dots report but got %s", recorder.Body.String())
+	}
+}
+
+func TestSuiteEndpointStatusReflectsOutcome(t *testing.T) {
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("passing suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			}),
+		suite.NewSequentialSuite("failing hook suite").
+			AfterAll("should fail", func(instance map[string]interface{}) error {
+				return fmt.Errorf("teardown failed")
+			}).
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			}),
+	})
+
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/passing-suite", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected 200 but got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/failing-hook-suite", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 but got %d", recorder.Code)
+	}
+
+	api.WithFailureStatus(http.StatusExpectationFailed)
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/failing-hook-suite", nil))
+	if recorder.Code != http.StatusExpectationFailed {
+		t.Errorf("expected 417 but got %d", recorder.Code)
+	}
+
+	for _, status := range []int{0, http.StatusOK, 1000} {
+		api.WithFailureStatus(status)
+	}
+	if problems := api.Problems(); len(problems) != 3 || problems[0].Kind != InvalidFailureStatusProblem {
+		t.Errorf("expected invalid failure statuses to be problems but got %v", problems)
+	}
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/failing-hook-suite", nil))
+	if recorder.Code != http.StatusExpectationFailed {
+		t.Errorf("expected invalid failure statuses to be ignored but got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/unknown-suite", nil))
+	var response ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || recorder.Code != http.StatusNotFound || response.Status != "404" {
+		t.Errorf("expected 404 error response but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSuiteEndpointResolvesParameters(t *testing.T) {
+	var tenant interface{}
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		Parameter("tenant", suite.IntParameter, nil).
+		It("returns 200", func(instance map[string]interface{}) error {
+			tenant = instance["tenant"]
+			return nil
+		})})
+
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/parent-suite?tenant=42&format=text", nil))
+	if recorder.Code != http.StatusOK || tenant != 42 {
+		t.Errorf("expected tenant 42 from query but got %v (%d)", tenant, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/parent-suite", strings.NewReader(`{"tenant": 7}`))
+	request.Header.Set("Content-Type", "application/json")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || tenant != 7 {
+		t.Errorf("expected tenant 7 from body but got %v (%d)", tenant, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/parent-suite?tenant=abc", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid parameter but got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/parent-suite?tenant=5&_=123&utm_source=mail", nil))
+	if recorder.Code != http.StatusOK || tenant != 5 {
+		t.Errorf("expected undeclared query parameters to be ignored but got %v (%d)", tenant, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/parent-suite?tenant=3", nil)
+	request.Header.Set("Content-Type", "application/json")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || tenant != 3 {
+		t.Errorf("expected an empty json body to carry no parameters but got %v (%d)", tenant, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/parent-suite", strings.NewReader(`{"tenant": 7, "region": "eu"}`))
+	request.Header.Set("Content-Type", "application/json")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for undeclared parameter in body but got %d", recorder.Code)
+	}
+}
+
+func TestHandlerCanBeMountedBelowPrefix(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})}).WithPrefix("/tests/")
+	mux := http.NewServeMux()
+	mux.Handle("/tests/", api.Handler())
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/tests/parent-suite", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected 200 for running /tests/parent-suite but got %d", recorder.Code)
+	}
+	for _, target := range []string{"/tests/", "/tests/parent-suite", "/tests/plan"} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected 200 for %s but got %d", target, recorder.Code)
+		}
+	}
+	recorder = httptest.NewRecorder()
+	api.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tests", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected 200 for /tests but got %d", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tests/unknown", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown endpoint but got %d", recorder.Code)
+	}
+
+	api.WithSchedule("unknown-suite", "* * * * *", nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tests/parent-suite", nil))
+	if recorder.Code != http.StatusInternalServerError || !strings.Contains(recorder.Body.String(), InvalidScheduleProblem) {
+		t.Errorf("expected 500 with the problems of a misconfigured Api but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSuiteEndpointRunsOnPostAndServesLastResultOnGet(t *testing.T) {
+	runs := 0
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			runs += 1
+			return nil
+		})})
+
+	if status := serve(t, api, http.MethodGet, "/parent-suite", nil, nil); status != http.StatusNotFound || runs != 0 {
+		t.Errorf("expected 404 without running before the first run but got %d after %d runs", status, runs)
+	}
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/parent-suite", nil))
+	etag, lastModified := recorder.Header().Get("ETag"), recorder.Header().Get("Last-Modified")
+	if recorder.Code != http.StatusOK || runs != 1 || etag == "" || lastModified == "" || !strings.Contains(recorder.Body.String(), `"total_passed":1`) {
+		t.Errorf("expected the stored result with validators but got %d after %d runs: %v %s", recorder.Code, runs, recorder.Header(), recorder.Body.String())
+	}
+	for header, value := range map[string]string{"If-None-Match": etag, "If-Modified-Since": lastModified} {
+		recorder = httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/parent-suite", nil)
+		request.Header.Set(header, value)
+		api.router().ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+			t.Errorf("expected 304 for %s but got %d", header, recorder.Code)
+		}
+	}
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/parent-suite?format=junit", nil)
+	request.Header.Set("If-None-Match", etag)
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("ETag") == etag {
+		t.Errorf("expected another representation to have another ETag but got %d %s", recorder.Code, recorder.Header().Get("ETag"))
+	}
+	for _, target := range []string{"/parent-suite/returns-200", "/all"} {
+		if status := serve(t, api, http.MethodGet, target, nil, nil); status != http.StatusMethodNotAllowed {
+			t.Errorf("expected GET %s to be refused but got %d", target, status)
+		}
+	}
+
+	api.WithRunOnGet(true)
+	for _, target := range []string{"/parent-suite", "/parent-suite/returns-200", "/all"} {
+		serve(t, api, http.MethodGet, target, nil, nil)
+	}
+	if runs != 4 {
+		t.Errorf("expected GET to run suites again with WithRunOnGet but got %d runs", runs)
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ListAction   = "list"
+	RunAction    = "run"
+	CancelAction = "cancel"
+)
+
+// defaultDenialRetention is the number of unauthorized attempts kept for
+// AccessDenials.
+const defaultDenialRetention = 100
+
+// Authenticator identifies the principal sending a request.
+type Authenticator interface {
+	// Authenticate returns the name of the principal sending r, or false if r
+	// does not carry credentials accepted by the Authenticator.
+	Authenticate(r *http.Request) (string, bool)
+	// Challenge is the WWW-Authenticate header of a response to a request
+	// that was not authenticated, or empty.
+	Challenge() string
+}
+
+// Authorizer decides whether principal may perform action (ListAction,
+// RunAction or CancelAction) on the suite served at endpoint.
+type Authorizer func(principal string, action string, endpoint string) bool
+
+// Rule allows Principals to perform Actions on the suites served at the
+// endpoints in Suites. "*" matches every principal, action or suite.
+type Rule struct {
+	Principals []string
+	Actions    []string
+	Suites     []string
+}
+
+// AccessDenial is an unauthorized attempt to use the Api.
+type AccessDenial struct {
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal"`
+	Action     string    `json:"action"`
+	Suite      string    `json:"suite"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	Status     int       `json:"status"`
+}
+
+type bearerAuthenticator struct {
+	tokens map[string]string
+}
+type basicAuthenticator struct {
+	users map[string]string
+}
+type certificateAuthenticator struct {
+	names []string
+}
+
+// BearerTokens authenticates requests with an "Authorization: Bearer" header
+// carrying one of the tokens, which are mapped to principal names.
+func BearerTokens(tokens map[string]string) Authenticator {
+	return &bearerAuthenticator{tokens: tokens}
+}
+
+// BasicAuth authenticates requests with HTTP basic authentication against
+// users, which maps user names to passwords. The principal is the user name.
+func BasicAuth(users map[string]string) Authenticator {
+	return &basicAuthenticator{users: users}
+}
+
+// ClientCertificates authenticates requests with a verified TLS client
+// certificate, whose subject common name is the principal. The server passed
+// to NewApiWithServer must request and verify client certificates. If names
+// are given, only certificates for those common names are accepted.
+func ClientCertificates(names ...string) Authenticator {
+	return &certificateAuthenticator{names: names}
+}
+
+func (authenticator *bearerAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	for candidate, principal := range authenticator.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			return principal, true
+		}
+	}
+	return "", false
+}
+func (authenticator *bearerAuthenticator) Challenge() string {
+	return `Bearer realm="gopher-jasmine"`
+}
+func (authenticator *basicAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	expected, ok := authenticator.users[user]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), []byte(password)) != 1 {
+		return "", false
+	}
+	return user, true
+}
+func (authenticator *basicAuthenticator) Challenge() string {
+	return `Basic realm="gopher-jasmine"`
+}
+func (authenticator *certificateAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if len(authenticator.names) == 0 || matches(authenticator.names, name) {
+		return name, true
+	}
+	return "", false
+}
+func (authenticator *certificateAuthenticator) Challenge() string {
+	return ""
+}
+
+// Rules returns an Authorizer allowing what any of rules allows and denying
+// everything else.
+func Rules(rules ...Rule) Authorizer {
+	return func(principal string, action string, endpoint string) bool {
+		for _, rule := range rules {
+			if matches(rule.Principals, principal) && matches(rule.Actions, action) && matches(rule.Suites, endpoint) {
+				return true
+			}
+		}
+		return false
+	}
+}
+func matches(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == "*" || candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// access holds the authentication and authorization settings of an Api and
+// the unauthorized attempts it recorded.
+type access struct {
+	mutex          sync.Mutex
+	authenticators []Authenticator
+	authorizer     Authorizer
+	denials        []AccessDenial
+}
+
+type principalKey struct{}
+
+// WithAuthentication requires every request to be authenticated by one of
+// authenticators. Requests that are not are answered with 401.
+func (api *Api) WithAuthentication(authenticators ...Authenticator) *Api {
+	api.access.authenticators = append(api.access.authenticators, authenticators...)
+	return api
+}
+
+// WithAuthorization restricts which principals may list, run and cancel which
+// suites. Without it, every authenticated principal may do everything.
+func (api *Api) WithAuthorization(authorizer Authorizer) *Api {
+	api.access.authorizer = authorizer
+	return api
+}
+
+// AccessDenials returns the most recent unauthorized attempts, oldest first.
+func (api *Api) AccessDenials() []AccessDenial {
+	api.access.mutex.Lock()
+	defer api.access.mutex.Unlock()
+	return append(make([]AccessDenial, 0, len(api.access.denials)), api.access.denials...)
+}
+
+// authenticate is a middleware rejecting requests none of the authenticators
+// accepts and passing the principal of the others on in their context. Probes
+// need no credentials.
+func (api *Api) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); len(api.access.authenticators) == 0 || (route != nil && probeRoutes[route.GetName()]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, authenticator := range api.access.authenticators {
+			if principal, ok := authenticator.Authenticate(r); ok {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+				return
+			}
+		}
+		for _, authenticator := range api.access.authenticators {
+			if challenge := authenticator.Challenge(); challenge != "" {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+		}
+		api.deny(r, "", "", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "Authentication required")
+	})
+}
+
+// principal returns the name of the principal that sent r, or an empty string
+// if the Api does not authenticate requests.
+func principal(r *http.Request) string {
+	name, _ := r.Context().Value(principalKey{}).(string)
+	return name
+}
+
+// allowed reports whether the sender of r may perform action on the suite
+// served at endpoint.
+func (api *Api) allowed(r *http.Request, action string, endpoint string) bool {
+	return api.access.authorizer == nil || api.access.authorizer(principal(r), action, endpoint)
+}
+
+// authorize is allowed, recording the attempt if it is not.
+func (api *Api) authorize(r *http.Request, action string, endpoint string) bool {
+	if api.allowed(r, action, endpoint) {
+		return true
+	}
+	api.deny(r, action, endpoint, http.StatusForbidden)
+	return false
+}
+func (api *Api) deny(r *http.Request, action string, endpoint string, status int) {
+	denial := AccessDenial{
+		Time:       time.Now(),
+		Principal:  principal(r),
+		Action:     action,
+		Suite:      endpoint,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		Status:     status,
+	}
+	api.access.mutex.Lock()
+	defer api.access.mutex.Unlock()
+	api.access.denials = append(api.access.denials, denial)
+	if len(api.access.denials) > defaultDenialRetention {
+		api.access.denials = api.access.denials[len(api.access.denials)-defaultDenialRetention:]
+	}
+}
+
+// forbidden answers a request that authorize rejected.
+func forbidden(w http.ResponseWriter, action string, endpoint string) {
+	writeError(w, http.StatusForbidden, forbiddenMessage(action, endpoint))
+}
+func forbiddenMessage(action string, endpoint string) string {
+	return fmt.Sprintf("Not allowed to %s suite '/%s'", action, endpoint)
+}
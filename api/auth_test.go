@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticationAndPerSuiteAuthorization(t *testing.T) {
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("smoke suite").It("returns 200", noop),
+		suite.NewSequentialSuite("staging suite").It("returns 200", noop),
+	}).
+		WithAuthentication(BearerTokens(map[string]string{"secret": "ci"}), BasicAuth(map[string]string{"alice": "wonderland"})).
+		WithAuthorization(Rules(
+			Rule{Principals: []string{"ci"}, Actions: []string{"*"}, Suites: []string{"*"}},
+			Rule{Principals: []string{"alice"}, Actions: []string{ListAction, RunAction}, Suites: []string{"smoke-suite"}},
+		))
+	request := func(target string, authorize func(r *http.Request)) *httptest.ResponseRecorder {
+		method := http.MethodPost
+		if target == "/" {
+			method = http.MethodGet
+		}
+		r := httptest.NewRequest(method, target, nil)
+		if authorize != nil {
+			authorize(r)
+		}
+		recorder := httptest.NewRecorder()
+		api.router().ServeHTTP(recorder, r)
+		return recorder
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }
+	alice := func(r *http.Request) { r.SetBasicAuth("alice", "wonderland") }
+
+	recorder := request("/smoke-suite", nil)
+	if recorder.Code != http.StatusUnauthorized || recorder.Header()["Www-Authenticate"] == nil {
+		t.Errorf("expected 401 with challenge but got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder = request("/staging-suite", bearer); recorder.Code != http.StatusOK {
+		t.Errorf("expected bearer token to run staging suite but got %d", recorder.Code)
+	}
+	if recorder = request("/smoke-suite", alice); recorder.Code != http.StatusOK {
+		t.Errorf("expected alice to run smoke suite but got %d", recorder.Code)
+	}
+	if recorder = request("/staging-suite", alice); recorder.Code != http.StatusForbidden {
+		t.Errorf("expected alice not to run staging suite but got %d", recorder.Code)
+	}
+	if recorder = request("/", alice); recorder.Body.String() != `["smoke-suite"]` {
+		t.Errorf("expected alice to list only the smoke suite but got %s", recorder.Body.String())
+	}
+
+	denials := api.AccessDenials()
+	if len(denials) != 2 || denials[0].Status != http.StatusUnauthorized || denials[1].Principal != "alice" || denials[1].Suite != "staging-suite" {
+		t.Errorf("expected unauthorized attempts to be recorded but got %v", denials)
+	}
+}
+
+func TestClientCertificatesAuthenticateByCommonName(t *testing.T) {
+	authenticator := ClientCertificates("ci")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := authenticator.Authenticate(r); ok {
+		t.Errorf("expected request without certificate not to be authenticated")
+	}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ci"}}}}}
+	if principal, ok := authenticator.Authenticate(r); !ok || principal != "ci" {
+		t.Errorf("expected principal ci but got '%s'", principal)
+	}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "intruder"}}}}}
+	if _, ok := authenticator.Authenticate(r); ok {
+		t.Errorf("expected unknown common name not to be authenticated")
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// createDashboardHandler serves a self-contained web UI listing the suites,
+// triggering runs through the runs endpoint and rendering their results and
+// the recent run history. All endpoints are addressed relative to the
+// dashboard, so it keeps working when the Api is mounted below a prefix.
+func createDashboardHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, dashboardHTML)
+	}
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gopher-jasmine</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #222; background: #f6f7f9; }
+  header { background: #2b3a4a; color: #fff; padding: 12px 24px; font-size: 20px; }
+  main { display: flex; gap: 24px; padding: 24px; align-items: flex-start; }
+  section { background: #fff; border: 1px solid #dde1e6; border-radius: 6px; padding: 16px; }
+  #suites { width: 280px; }
+  #details { flex: 1; min-width: 0; }
+  h2 { font-size: 16px; margin: 0 0 12px 0; }
+  ul { list-style: none; padding-left: 18px; margin: 4px 0; }
+  #suite-list { padding: 0; }
+  #suite-list li { display: flex; justify-content: space-between; align-items: center; padding: 4px 0; }
+  button { cursor: pointer; border: 1px solid #8a96a3; background: #fff; border-radius: 4px; padding: 2px 10px; }
+  table { border-collapse: collapse; width: 100%; font-size: 14px; }
+  td, th { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eceef1; }
+  tr.history { cursor: pointer; }
+  tr.history:hover { background: #f0f3f6; }
+  .tag { font-size: 11px; background: #e4e8ee; border-radius: 3px; padding: 0 4px; margin-left: 4px; }
+  .PASSED { color: #1a7f37; }
+  .FAILED { color: #cf222e; }
+  .SKIPPED { color: #8a96a3; }
+  .exception { color: #cf222e; font-size: 13px; margin-left: 18px; }
+  .message { color: #57606a; font-size: 13px; margin-left: 18px; white-space: pre-wrap; }
+  .duration { color: #8a96a3; font-size: 12px; margin-left: 6px; }
+  .suite-name { font-weight: 600; }
+  .totals { margin: 8px 0 16px 0; }
+</style>
+</head>
+<body>
+<header>gopher-jasmine</header>
+<main>
+  <section id="suites">
+    <h2>Suites</h2>
+    <ul id="suite-list"></ul>
+  </section>
+  <section id="details">
+    <h2 id="run-title">Select a suite to run</h2>
+    <div id="run-totals" class="totals"></div>
+    <div id="run-tree"></div>
+    <h2>Recent runs</h2>
+    <table>
+      <thead><tr><th>Suite</th><th>Status</th><th>Started</th><th>Passed</th><th>Failed</th><th>Skipped</th></tr></thead>
+      <tbody id="history"></tbody>
+    </table>
+  </section>
+</main>
+<script>
+(function () {
+  var base = window.location.pathname.replace(/\/dashboard\/?$/, "");
+  var markers = { PASSED: "✓", FAILED: "✗", SKIPPED: "-" };
+
+  function element(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) { node.className = className; }
+    if (text !== undefined) { node.textContent = text; }
+    return node;
+  }
+  function request(method, path, body) {
+    var options = { method: method, headers: {} };
+    if (body) {
+      options.headers["Content-Type"] = "application/json";
+      options.body = JSON.stringify(body);
+    }
+    return fetch(base + path, options).then(function (response) { return response.json(); });
+  }
+  function duration(nanoseconds) {
+    return (nanoseconds / 1e6).toFixed(1) + " ms";
+  }
+  function exception(label, value) {
+    if (!value) { return null; }
+    return element("div", "exception", label + " '" + value.name + "': " + value.message);
+  }
+  function renderSpec(spec) {
+    var item = element("li");
+    item.appendChild(element("span", spec.status, markers[spec.status] + " " + spec.name));
+    item.appendChild(element("span", "duration", duration(spec.duration)));
+    if (spec.message) { item.appendChild(element("div", "message", spec.message)); }
+    [exception("BeforeEach", spec.before_each_exception), exception("AfterEach", spec.after_each_exception)]
+      .concat((spec.cleanup_exceptions || []).map(function (e) { return exception("Cleanup", e); }))
+      .forEach(function (node) { if (node) { item.appendChild(node); } });
+    return item;
+  }
+  function renderResult(result) {
+    var item = element("li");
+    item.appendChild(element("span", "suite-name", result.name));
+    item.appendChild(element("span", "duration", duration(result.duration)));
+    [exception("BeforeAll", result.before_all_exception), exception("AfterAll", result.after_all_exception)]
+      .concat((result.cleanup_exceptions || []).map(function (e) { return exception("Cleanup", e); }))
+      .forEach(function (node) { if (node) { item.appendChild(node); } });
+    var list = element("ul");
+    (result.spec_results || []).forEach(function (spec) { list.appendChild(renderSpec(spec)); });
+    (result.children || []).forEach(function (child) { list.appendChild(renderResult(child)); });
+    item.appendChild(list);
+    return item;
+  }
+  function renderRun(run) {
+    document.getElementById("run-title").textContent = run.suite + " (" + run.status + ")";
+    var totals = document.getElementById("run-totals");
+    var tree = document.getElementById("run-tree");
+    tree.innerHTML = "";
+    if (run.result) {
+      totals.textContent = "passed: " + run.result.total_passed + ", failed: " + run.result.total_failed +
+        ", skipped: " + run.result.total_skipped + ", took " + duration(run.result.duration);
+      var root = element("ul");
+      root.appendChild(renderResult(run.result));
+      tree.appendChild(root);
+    } else {
+      totals.textContent = (run.spec_results || []).length + " specs finished so far";
+      var partial = element("ul");
+      (run.spec_results || []).forEach(function (spec) {
+        var item = renderSpec(spec);
+        item.firstChild.textContent = markers[spec.status] + " " + spec.path;
+        partial.appendChild(item);
+      });
+      tree.appendChild(partial);
+    }
+  }
+  function showRun(id) {
+    return request("GET", "/runs/" + id).then(function (run) {
+      renderRun(run);
+      return run;
+    });
+  }
+  function follow(id) {
+    var events = new EventSource(base + "/runs/" + id + "/events");
+    ["spec_passed", "spec_failed", "spec_skipped", "suite_finished"].forEach(function (type) {
+      events.addEventListener(type, function () { showRun(id); });
+    });
+    events.addEventListener("run_finished", function () {
+      events.close();
+      showRun(id).then(loadHistory);
+    });
+    events.onerror = function () {
+      events.close();
+      showRun(id).then(loadHistory);
+    };
+  }
+  function startRun(endpoint) {
+    request("POST", "/runs", { suite: endpoint }).then(function (run) {
+      if (!run.id) {
+        document.getElementById("run-title").textContent = run.message;
+        return;
+      }
+      renderRun(run);
+      loadHistory();
+      follow(run.id);
+    });
+  }
+  function loadSuites() {
+    Promise.all([request("GET", "/"), request("GET", "/plan")]).then(function (answers) {
+      var list = document.getElementById("suite-list");
+      list.innerHTML = "";
+      answers[0].forEach(function (endpoint, index) {
+        var plan = answers[1][index];
+        var item = element("li");
+        var name = element("span", null, plan.name);
+        (plan.tags || []).forEach(function (tag) { name.appendChild(element("span", "tag", tag)); });
+        var button = element("button", null, "Run");
+        button.onclick = function () { startRun(endpoint); };
+        item.appendChild(name);
+        item.appendChild(button);
+        list.appendChild(item);
+      });
+    });
+  }
+  function loadHistory() {
+    request("GET", "/runs").then(function (runs) {
+      var body = document.getElementById("history");
+      body.innerHTML = "";
+      runs.forEach(function (run) {
+        var row = element("tr", "history");
+        var result = run.result || { total_passed: "", total_failed: "", total_skipped: "" };
+        [run.suite, run.status, new Date(run.started_at).toLocaleString(),
+          result.total_passed, result.total_failed, result.total_skipped].forEach(function (value) {
+          row.appendChild(element("td", null, String(value)));
+        });
+        row.onclick = function () { showRun(run.id); };
+        body.appendChild(row);
+      });
+    });
+  }
+  loadSuites();
+  loadHistory();
+})();
+</script>
+</body>
+</html>
+`
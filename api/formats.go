@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/report"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSONFormat  = "json"
+	JUnitFormat = "junit"
+	TAPFormat   = "tap"
+	TextFormat  = "text"
+	DotsFormat  = "dots"
+)
+
+// formatContentTypes maps the formats results can be served in to the media
+// types requested through the Accept header.
+var formatContentTypes = map[string][]string{
+	JSONFormat:  {"application/json"},
+	JUnitFormat: {"application/xml", "text/xml", "application/junit+xml"},
+	TAPFormat:   {"text/x-tap", "application/x-tap"},
+	TextFormat:  {"text/plain"},
+	DotsFormat:  {},
+}
+
+// resultFormat picks the format to serve a result in from the format query
+// parameter or else the Accept header. A format is only picked from the
+// Accept header if one of its media types is among those the client prefers
+// most, by q-value; otherwise, and when a wildcard is preferred as much, it
+// defaults to JSON.
+func resultFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", fmt.Errorf("Unknown format '%s'", format)
+		}
+		return format, nil
+	}
+	ranges := acceptedMediaRanges(r.Header.Get("Accept"))
+	best := 0.0
+	for _, accepted := range ranges {
+		if accepted.q > best {
+			best = accepted.q
+		}
+	}
+	picked := ""
+	for _, accepted := range ranges {
+		if accepted.q < best || accepted.q == 0 {
+			continue
+		}
+		if strings.HasSuffix(accepted.mediaType, "/*") {
+			return JSONFormat, nil
+		}
+		if format, ok := mediaTypeFormat(accepted.mediaType); ok && (picked == "" || format == JSONFormat) {
+			picked = format
+		}
+	}
+	if picked == "" {
+		return JSONFormat, nil
+	}
+	return picked, nil
+}
+
+// mediaRange is a media range of an Accept header with its q-value.
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// acceptedMediaRanges parses an Accept header. Ranges without a valid q-value
+// have q=1.
+func acceptedMediaRanges(header string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, accepted := range strings.Split(header, ",") {
+		parameters := strings.Split(accepted, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parameters[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, parameter := range parameters[1:] {
+			parameter = strings.TrimSpace(parameter)
+			if strings.HasPrefix(parameter, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(parameter, "q="), 64); err == nil && value >= 0 && value <= 1 {
+					q = value
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// mediaTypeFormat returns the format served for mediaType.
+func mediaTypeFormat(mediaType string) (string, bool) {
+	for format, contentTypes := range formatContentTypes {
+		for _, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// serialise renders result in format, returning the body and its content type.
+func serialise(result suite.Result, format string) ([]byte, string, error) {
+	switch format {
+	case JUnitFormat:
+		body, err := report.JUnit(result)
+		return body, "application/xml", err
+	case TAPFormat:
+		return []byte(report.TAP(result)), "text/x-tap; charset=utf-8", nil
+	case TextFormat:
+		return []byte(report.Text(result)), "text/plain; charset=utf-8", nil
+	case DotsFormat:
+		return []byte(report.Dots(result)), "text/plain; charset=utf-8", nil
+	default:
+		body, err := json.Marshal(result)
+		return body, "application/json", err
+	}
+}
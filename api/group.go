@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AllSuitesName is the name of the Result combining the results of a run of
+// several top-level suites.
+const AllSuitesName = "all suites"
+
+// createRunAllHandler runs every suite the sender may list, or those tagged
+// with one of the tag query parameters, and responds with a Result having one
+// child per suite and the overall totals. With concurrent=true the suites run
+// at the same time, otherwise one after the other. If no suite is selected it
+// responds with 404 rather than an empty result.
+func createRunAllHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !api.runsOn(r) {
+			runRequiresPost(w, r)
+			return
+		}
+		format, err := resultFormat(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		concurrent := false
+		if value := r.URL.Query().Get("concurrent"); value != "" {
+			if concurrent, err = strconv.ParseBool(value); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid value '%s' for concurrent", value))
+				return
+			}
+		}
+		suites := make([]suite.Suite, 0)
+		for _, s := range api.listable(r) {
+			if hasAnyTag(s, r.URL.Query()["tag"]) {
+				suites = append(suites, s)
+			}
+		}
+		if len(suites) == 0 {
+			if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("No suite is tagged with '%s'", strings.Join(tags, "' or '")))
+			} else {
+				writeError(w, http.StatusNotFound, "No suite to run")
+			}
+			return
+		}
+		for _, s := range suites {
+			if !api.authorize(r, RunAction, slugify(s.GetName())) {
+				forbidden(w, RunAction, slugify(s.GetName()))
+				return
+			}
+		}
+		values, err := requestParameterValues(r, suites...)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		options := make([]suite.RunOptions, len(suites))
+		used := make(map[string]bool)
+		for i, s := range suites {
+			declared := declaredValues(s, values)
+			for name := range declared {
+				used[name] = true
+			}
+			parameters, err := suite.ResolveParameters(s, declared)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("%s of suite '%s'", err.Error(), s.GetName()))
+				return
+			}
+			options[i] = suite.RunOptions{Parameters: parameters}
+		}
+		for name := range values {
+			if !used[name] {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("parameter '%s' is not declared by any suite", name))
+				return
+			}
+		}
+		result, err := api.runs.runAll(suites, options, concurrent)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		body, contentType, err := serialise(result, format)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get results with error: %s", err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(api.resultStatus(result))
+		w.Write(body)
+	}
+}
+
+// runAll runs suites with options, one after the other or concurrently, and
+// combines their results.
+func (manager *runManager) runAll(suites []suite.Suite, options []suite.RunOptions, concurrent bool) (suite.Result, error) {
+	start := time.Now()
+	results := make([]suite.Result, len(suites))
+	errs := make([]error, len(suites))
+	if concurrent {
+		var wg sync.WaitGroup
+		for i := range suites {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = manager.runNow(suites[i], options[i], AllTrigger)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range suites {
+			results[i], errs[i] = manager.runNow(suites[i], options[i], AllTrigger)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return suite.Result{}, err
+		}
+	}
+	result := suite.Result{
+		Name:        AllSuitesName,
+		StartedAt:   start,
+		Duration:    time.Since(start),
+		SpecResults: make([]suite.SpecResult, 0),
+		Children:    results,
+	}
+	return result.CalculateResults(), nil
+}
+
+// hasAnyTag reports whether s is tagged with one of tags, or tags is empty.
+func hasAnyTag(s suite.Suite, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range suite.GetTags(s) {
+		for _, wanted := range tags {
+			if tag == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
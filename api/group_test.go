@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunAllCombinesResultsOfTaggedSuites(t *testing.T) {
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("smoke suite").Tag("smoke").It("returns 200", noop).It("returns 201", noop),
+		suite.NewConcurrentSuite("regression suite").Tag("regression").
+			It("returns 500", func(instance map[string]interface{}) error {
+				return fmt.Errorf("expected 200 but got 500")
+			}),
+		suite.NewSequentialSuite("other smoke suite").Tag("smoke").
+			Parameter("tenant", suite.IntParameter, 1).
+			It("returns 200", noop),
+	})
+
+	for _, target := range []string{"/all?tag=smoke&tenant=3", "/all?tag=smoke&concurrent=true"} {
+		recorder := httptest.NewRecorder()
+		api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+		var result suite.Result
+		json.Unmarshal(recorder.Body.Bytes(), &result)
+		if recorder.Code != http.StatusOK || len(result.Children) != 2 || result.TotalPassed != 3 {
+			t.Errorf("expected both smoke suites to pass for %s but got %d %s", target, recorder.Code, recorder.Body.String())
+		}
+		if len(result.Children) == 2 && (result.Children[0].Name != "smoke suite" || result.Children[1].Name != "other smoke suite") {
+			t.Errorf("expected results in registration order but got %s and %s", result.Children[0].Name, result.Children[1].Name)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/all", nil))
+	var result suite.Result
+	json.Unmarshal(recorder.Body.Bytes(), &result)
+	if recorder.Code != http.StatusInternalServerError || result.TotalPassed != 3 || result.TotalFailed != 1 {
+		t.Errorf("expected every suite to run and the failure to be reported but got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	if status := serve(t, api, http.MethodPost, "/all?tag=smoek", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a tag no suite has but got %d", status)
+	}
+
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/all?region=eu", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected undeclared query parameter to be ignored but got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/all", strings.NewReader(`{"region": "eu"}`))
+	request.Header.Set("Content-Type", "application/json")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for undeclared parameter in body but got %d", recorder.Code)
+	}
+}
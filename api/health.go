@@ -0,0 +1,230 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"time"
+)
+
+// Health of a suite according to its recorded runs.
+const (
+	HealthyStatus = "healthy"
+	FailingStatus = "failing"
+	StaleStatus   = "stale"
+	UnknownStatus = "unknown"
+)
+
+// UnhealthyStatus is the aggregate status of an Api with a suite that is not
+// healthy.
+const UnhealthyStatus = "unhealthy"
+
+const InvalidHealthProblem = "invalid_health"
+
+// RunHeader carries the ID of the run whose result a response holds.
+const RunHeader = "X-Gopher-Jasmine-Run"
+
+// probeRoutes name the routes answering probes, which are not authenticated.
+var probeRoutes = map[string]bool{"liveness probe": true, "readiness probe": true}
+
+// HealthThresholds decide when the recorded runs of a suite make it
+// unhealthy.
+type HealthThresholds struct {
+	// MaxAge is how long ago the last run of the suite may have finished
+	// before it is stale. Zero means runs never go stale.
+	MaxAge time.Duration
+	// Failures is the number of consecutive failed runs that make the suite
+	// failing, 1 if zero.
+	Failures int
+	// Optional suites are reported but do not affect the aggregate status.
+	Optional bool
+	// RequireRun makes a suite that has not run yet unhealthy. By default
+	// such a suite is reported as unknown without affecting the aggregate
+	// status, so that a fresh instance becomes ready.
+	RequireRun bool
+}
+
+// SuiteHealth is the health of a suite. ConsecutiveFailures is only counted up
+// to the Failures threshold of the suite.
+type SuiteHealth struct {
+	Suite               string     `json:"suite"`
+	Status              string     `json:"status"`
+	Message             string     `json:"message"`
+	Optional            bool       `json:"optional"`
+	LastRunID           string     `json:"last_run_id"`
+	LastFinishedAt      *time.Time `json:"last_finished_at"`
+	LastSucceeded       *bool      `json:"last_succeeded"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// HealthReport is the aggregate health of the suites of an Api.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Suites []SuiteHealth `json:"suites"`
+}
+
+type probeResponse struct {
+	Status string `json:"status"`
+}
+
+// WithHealth applies thresholds to the suites served at endpoints, or to
+// every suite without thresholds of its own if no endpoints are given.
+// Without it, a suite is healthy unless its last run failed.
+func (api *Api) WithHealth(thresholds HealthThresholds, endpoints ...string) *Api {
+	if len(endpoints) == 0 {
+		api.defaultHealth = thresholds
+		return api
+	}
+	for _, endpoint := range endpoints {
+		if _, ok := api.findSuite(endpoint); !ok {
+			api.problems = append(api.problems, suite.Problem{
+				Kind:    InvalidHealthProblem,
+				Path:    endpoint,
+				Message: fmt.Sprintf("health thresholds of '/%s' do not address a suite", endpoint),
+			})
+			fmt.Printf("INVALID Suite: %s\n", api.problems[len(api.problems)-1])
+			continue
+		}
+		api.health[endpoint] = thresholds
+	}
+	return api
+}
+
+// suiteHealth judges the health of s by its recorded runs as of now, leaving
+// out partial and cancelled runs.
+func (api *Api) suiteHealth(s suite.Suite, now time.Time) (SuiteHealth, error) {
+	endpoint := slugify(s.GetName())
+	thresholds := api.healthThresholds(endpoint)
+	if thresholds.Failures <= 0 {
+		thresholds.Failures = 1
+	}
+	health := SuiteHealth{Suite: endpoint, Status: HealthyStatus, Optional: thresholds.Optional}
+	err := completeRuns(api.runs.history, s.GetName(), func(entry HistoryEntry) bool {
+		if health.LastFinishedAt == nil {
+			succeeded := entry.Result.Succeeded()
+			health.LastRunID = entry.ID
+			health.LastFinishedAt = &entry.FinishedAt
+			health.LastSucceeded = &succeeded
+		}
+		if entry.Result.Succeeded() {
+			return false
+		}
+		health.ConsecutiveFailures += 1
+		return health.ConsecutiveFailures < thresholds.Failures
+	})
+	if err != nil {
+		return health, err
+	}
+	switch {
+	case health.LastFinishedAt == nil:
+		health.Status = UnknownStatus
+		health.Message = "has not run yet"
+	case health.ConsecutiveFailures >= thresholds.Failures:
+		health.Status = FailingStatus
+		health.Message = fmt.Sprintf("the last %d runs failed", health.ConsecutiveFailures)
+	case thresholds.MaxAge > 0 && now.Sub(*health.LastFinishedAt) > thresholds.MaxAge:
+		health.Status = StaleStatus
+		health.Message = fmt.Sprintf("the last run finished %s ago", now.Sub(*health.LastFinishedAt).Round(time.Second))
+	}
+	return health, nil
+}
+
+// healthThresholds returns the thresholds applied to the suite served at
+// endpoint.
+func (api *Api) healthThresholds(endpoint string) HealthThresholds {
+	if thresholds, ok := api.health[endpoint]; ok {
+		return thresholds
+	}
+	return api.defaultHealth
+}
+
+// healthReport judges the health of the suites served at the endpoints for
+// which include returns true. The aggregate status is healthy if every suite
+// that is not optional is, or has not run yet and does not require a run.
+func (api *Api) healthReport(include func(endpoint string) bool) (HealthReport, error) {
+	report := HealthReport{Status: HealthyStatus, Suites: make([]SuiteHealth, 0, len(api.suites))}
+	now := time.Now()
+	for _, s := range api.suites {
+		if !include(slugify(s.GetName())) {
+			continue
+		}
+		health, err := api.suiteHealth(s, now)
+		if err != nil {
+			return report, err
+		}
+		unknown := health.Status == UnknownStatus && !api.healthThresholds(health.Suite).RequireRun
+		if health.Status != HealthyStatus && !health.Optional && !unknown {
+			report.Status = UnhealthyStatus
+		}
+		report.Suites = append(report.Suites, health)
+	}
+	return report, nil
+}
+
+// createHealthHandler reports the health of the suites the sender may list,
+// with 200 if the aggregate status over those suites is healthy and 503
+// otherwise.
+func createHealthHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := api.healthReport(func(endpoint string) bool {
+			return api.allowed(r, ListAction, endpoint)
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read history with error: %s", err.Error()))
+			return
+		}
+		writeJSON(w, healthStatus(report.Status == HealthyStatus), report)
+	}
+}
+
+// createReadinessHandler answers readiness probes: 200 while the suites are
+// healthy, 503 once they are not or the Api is shutting down.
+func createReadinessHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.runs.isClosed() {
+			writeJSON(w, http.StatusServiceUnavailable, probeResponse{Status: "shutting down"})
+			return
+		}
+		report, err := api.healthReport(func(endpoint string) bool {
+			return true
+		})
+		if err != nil {
+			report.Status = UnhealthyStatus
+		}
+		writeJSON(w, healthStatus(report.Status == HealthyStatus), probeResponse{Status: report.Status})
+	}
+}
+
+// createLivenessHandler answers liveness probes, which only check that the
+// server responds.
+func createLivenessHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, probeResponse{Status: "alive"})
+	}
+}
+func healthStatus(healthy bool) int {
+	if healthy {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// createLastResultHandler responds with the result of the last recorded run
+// of the suite named by the path, in the format requested, without running
+// anything.
+func createLastResultHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := mux.Vars(r)["suite"]
+		s, ok := api.findSuite(endpoint)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No suite is served at '/%s'", endpoint))
+			return
+		}
+		if !api.authorize(r, ListAction, endpoint) {
+			forbidden(w, ListAction, endpoint)
+			return
+		}
+		api.writeLastResult(w, r, s)
+	}
+}
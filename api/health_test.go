@@ -0,0 +1,233 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthFollowsThresholdsOfRecordedRuns(t *testing.T) {
+	failing := false
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("parent suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				if failing {
+					return fmt.Errorf("expected 200 but got 500")
+				}
+				return nil
+			}),
+		suite.NewSequentialSuite("nightly suite"),
+	}).
+		WithHealth(HealthThresholds{Failures: 2}, "parent-suite").
+		WithHealth(HealthThresholds{Optional: true}, "nightly-suite")
+
+	var report HealthReport
+	if status := serve(t, api, http.MethodGet, "/health", nil, &report); status != http.StatusOK || report.Suites[0].Status != UnknownStatus {
+		t.Errorf("expected 200 with unknown suites before the first run but got %d and %+v", status, report)
+	}
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, nil); status != http.StatusOK {
+		t.Errorf("expected readiness before the first run but got %d", status)
+	}
+	if status := serve(t, api, http.MethodGet, "/results/parent-suite", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for the last result before the first run but got %d", status)
+	}
+	for i, fail := range []bool{false, true, true} {
+		failing = fail
+		serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+		status := serve(t, api, http.MethodGet, "/health", nil, &report)
+		if i < 2 && (status != http.StatusOK || report.Status != HealthyStatus || report.Suites[1].Status != UnknownStatus) {
+			t.Errorf("expected healthy after run %d but got %d and %+v", i, status, report)
+		}
+	}
+	if report.Status != UnhealthyStatus || report.Suites[0].Status != FailingStatus || report.Suites[0].ConsecutiveFailures != 2 {
+		t.Errorf("expected failing after two failed runs but got %+v", report)
+	}
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to fail but got %d", status)
+	}
+
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/results/parent-suite", nil))
+	if recorder.Code != http.StatusInternalServerError || recorder.Header().Get(RunHeader) != report.Suites[0].LastRunID {
+		t.Errorf("expected the failed last result of run '%s' but got %d for run '%s'", report.Suites[0].LastRunID, recorder.Code, recorder.Header().Get(RunHeader))
+	}
+}
+
+func TestHealthCanRequireARun(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})}).
+		WithHealth(HealthThresholds{RequireRun: true})
+
+	var probe probeResponse
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, &probe); status != http.StatusServiceUnavailable || probe.Status != UnhealthyStatus {
+		t.Errorf("expected readiness to wait for the first run but got %d and %+v", status, probe)
+	}
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, nil); status != http.StatusOK {
+		t.Errorf("expected readiness after the first run but got %d", status)
+	}
+}
+
+func TestPartialRunsDoNotCountAsRunsOfTheSuite(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("fails", func(instance map[string]interface{}) error {
+			return fmt.Errorf("expected 200 but got 500")
+		}).
+		It("passes", func(instance map[string]interface{}) error {
+			return nil
+		})})
+
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+	var report HealthReport
+	serve(t, api, http.MethodGet, "/health", nil, &report)
+	failedRun := report.Suites[0].LastRunID
+	if status := serve(t, api, http.MethodPost, "/parent-suite/passes", nil, nil); status != http.StatusOK {
+		t.Errorf("expected the selected spec to pass but got %d", status)
+	}
+
+	if status := serve(t, api, http.MethodGet, "/health", nil, &report); status != http.StatusServiceUnavailable || report.Suites[0].LastRunID != failedRun {
+		t.Errorf("expected the partial run not to change the health but got %d and %+v", status, report)
+	}
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/parent-suite", nil))
+	if recorder.Code != http.StatusInternalServerError || recorder.Header().Get(RunHeader) != failedRun {
+		t.Errorf("expected the last full run '%s' but got %d for run '%s'", failedRun, recorder.Code, recorder.Header().Get(RunHeader))
+	}
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), `gopher_jasmine_suite_runs_total{suite="parent-suite",result="succeeded"} 0`) {
+		t.Errorf("expected the partial run not to be counted but got %s", recorder.Body.String())
+	}
+	var page HistoryPage
+	serve(t, api, http.MethodGet, "/history", nil, &page)
+	if page.Total != 2 || !page.Entries[0].Partial() || page.Entries[0].Select[0] != "parent suite > passes" {
+		t.Errorf("expected the partial run to be recorded with its selection but got %+v", page)
+	}
+}
+
+func TestCancelledRunsDoNotCountAsRunsOfTheSuite(t *testing.T) {
+	failing := true
+	release := make(chan bool, 1)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("1: waits", func(instance map[string]interface{}) error {
+			if !failing {
+				<-release
+			}
+			return nil
+		}).
+		It("2: fails", func(instance map[string]interface{}) error {
+			if failing {
+				return fmt.Errorf("expected 200 but got 500")
+			}
+			return nil
+		})})
+
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+	var report HealthReport
+	serve(t, api, http.MethodGet, "/health", nil, &report)
+	failedRun := report.Suites[0].LastRunID
+	failing = false
+	var run Run
+	serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, &run)
+	waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.Status == RunningRun
+	})
+	serve(t, api, http.MethodDelete, "/runs/"+run.ID, nil, nil)
+	release <- true
+	run = waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.FinishedAt != nil
+	})
+	if run.Result == nil || !run.Result.Succeeded() || run.Result.SpecResults[1].Message != "cancelled" {
+		t.Fatalf("expected a cancelled run that looks successful but got %+v", run)
+	}
+
+	if status := serve(t, api, http.MethodGet, "/health", nil, &report); status != http.StatusServiceUnavailable || report.Suites[0].LastRunID != failedRun {
+		t.Errorf("expected the cancelled run not to change the health but got %d and %+v", status, report)
+	}
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/parent-suite", nil))
+	if recorder.Code != http.StatusInternalServerError || recorder.Header().Get(RunHeader) != failedRun {
+		t.Errorf("expected the last complete run '%s' but got %d for run '%s'", failedRun, recorder.Code, recorder.Header().Get(RunHeader))
+	}
+	recorder = httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), `gopher_jasmine_suite_runs_total{suite="parent-suite",result="succeeded"} 0`) {
+		t.Errorf("expected the cancelled run not to be counted but got %s", recorder.Body.String())
+	}
+	var entry HistoryEntry
+	serve(t, api, http.MethodGet, "/history/"+run.ID, nil, &entry)
+	if !entry.Cancelled || entry.Complete() {
+		t.Errorf("expected the run to be recorded as cancelled but got %+v", entry)
+	}
+}
+
+func TestHealthTurnsStaleAndProbesNeedNoCredentials(t *testing.T) {
+	history := NewMemoryHistory(10)
+	finishedAt := time.Now().Add(-2 * time.Hour)
+	history.Record(HistoryEntry{ID: "1", Suite: "parent suite", FinishedAt: finishedAt, Result: suite.Result{Name: "parent suite", StartedAt: finishedAt}})
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})}).
+		WithHistory(history).
+		WithHealth(HealthThresholds{MaxAge: time.Hour}).
+		WithHealth(HealthThresholds{}, "unknown-suite").
+		WithAuthentication(BearerTokens(map[string]string{"token": "monitor"}))
+
+	if len(api.Problems()) != 1 || api.Problems()[0].Kind != InvalidHealthProblem {
+		t.Errorf("expected thresholds of an unknown suite to be a problem but got %v", api.Problems())
+	}
+	if status := serve(t, api, http.MethodGet, "/health", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected the health report to require credentials but got %d", status)
+	}
+	if status := serve(t, api, http.MethodGet, "/health/live", nil, nil); status != http.StatusOK {
+		t.Errorf("expected liveness without credentials but got %d", status)
+	}
+	var probe probeResponse
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, &probe); status != http.StatusServiceUnavailable || probe.Status != UnhealthyStatus {
+		t.Errorf("expected a stale suite to fail readiness but got %d and %+v", status, probe)
+	}
+	health, _ := api.suiteHealth(api.suites[0], time.Now())
+	if health.Status != StaleStatus {
+		t.Errorf("expected a run finished 2 hours ago to be stale but got %+v", health)
+	}
+}
+
+func TestHealthOnlyJudgesTheSuitesTheSenderMayList(t *testing.T) {
+	history := NewMemoryHistory(10)
+	for _, id := range []string{"1", "2", "3"} {
+		history.Record(HistoryEntry{ID: id, Suite: "failing suite", FinishedAt: time.Now(), Result: suite.Result{Name: "failing suite", TotalFailed: 1}})
+	}
+	history.Record(HistoryEntry{ID: "4", Suite: "passing suite", FinishedAt: time.Now(), Result: suite.Result{Name: "passing suite"}})
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("failing suite").It("returns 200", noop),
+		suite.NewSequentialSuite("passing suite").It("returns 200", noop),
+	}).
+		WithHistory(history).
+		WithAuthentication(BearerTokens(map[string]string{"token": "monitor"})).
+		WithAuthorization(Rules(Rule{Principals: []string{"monitor"}, Actions: []string{ListAction}, Suites: []string{"passing-suite"}}))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/health", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	api.router().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || strings.Contains(recorder.Body.String(), "failing-suite") {
+		t.Errorf("expected 200 for the only suite the sender may list but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if status := serve(t, api, http.MethodGet, "/health/ready", nil, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to judge every suite but got %d", status)
+	}
+	health, _ := api.suiteHealth(api.suites[0], time.Now())
+	if health.Status != FailingStatus || health.ConsecutiveFailures != 1 {
+		t.Errorf("expected failures to be counted up to the threshold but got %+v", health)
+	}
+}
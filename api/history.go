@@ -0,0 +1,364 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How a recorded run was started.
+const (
+	EndpointTrigger  = "endpoint"
+	AllTrigger       = "all"
+	RunsTrigger      = "runs"
+	WebSocketTrigger = "websocket"
+)
+
+const (
+	// defaultHistoryRetention is the number of runs kept by the history of an
+	// Api unless WithHistory says otherwise.
+	defaultHistoryRetention = 1000
+	defaultHistoryPageSize  = 20
+	maxHistoryPageSize      = 500
+)
+
+// HistoryEntry is a finished run of a top-level suite.
+type HistoryEntry struct {
+	ID         string                 `json:"id"`
+	Suite      string                 `json:"suite"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Select     []string               `json:"select,omitempty"`
+	Cancelled  bool                   `json:"cancelled"`
+	Trigger    string                 `json:"trigger"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt time.Time              `json:"finished_at"`
+	Result     suite.Result           `json:"result"`
+}
+
+// Partial reports whether the run was restricted to some specs or nested
+// suites, in which case it does not tell whether the suite passes.
+func (entry HistoryEntry) Partial() bool {
+	return len(entry.Select) > 0
+}
+
+// Complete reports whether the run was neither partial nor cancelled. Only
+// complete runs tell whether the suite passes.
+func (entry HistoryEntry) Complete() bool {
+	return !entry.Partial() && !entry.Cancelled
+}
+
+// HistoryPage is a page of the run history, most recent run first.
+type HistoryPage struct {
+	Entries []HistoryEntry `json:"entries"`
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+}
+
+// HistoryStore records finished runs. Implementations must be safe for
+// concurrent use.
+type HistoryStore interface {
+	// Record stores entry, possibly evicting the oldest entries.
+	Record(entry HistoryEntry) error
+	// Get returns the entry with id.
+	Get(id string) (HistoryEntry, bool, error)
+	// List returns up to limit entries of the suite called name, or of every
+	// suite if name is empty, skipping the offset most recent ones, and the
+	// number of entries there are in total.
+	List(name string, offset int, limit int) ([]HistoryEntry, int, error)
+}
+
+// memoryHistory keeps the most recent runs in memory, oldest first.
+type memoryHistory struct {
+	mutex   sync.Mutex
+	entries []HistoryEntry
+	retain  int
+}
+
+// NewMemoryHistory returns a HistoryStore keeping the retain most recent runs
+// in memory.
+func NewMemoryHistory(retain int) HistoryStore {
+	return &memoryHistory{entries: make([]HistoryEntry, 0), retain: retain}
+}
+func (history *memoryHistory) Record(entry HistoryEntry) error {
+	history.record(entry)
+	return nil
+}
+
+// record stores entry and returns the entries evicted to make room for it.
+func (history *memoryHistory) record(entry HistoryEntry) []HistoryEntry {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	history.entries = append(history.entries, entry)
+	if history.retain <= 0 || len(history.entries) <= history.retain {
+		return nil
+	}
+	evicted := append(make([]HistoryEntry, 0), history.entries[:len(history.entries)-history.retain]...)
+	history.entries = append(make([]HistoryEntry, 0, history.retain), history.entries[len(history.entries)-history.retain:]...)
+	return evicted
+}
+func (history *memoryHistory) Get(id string) (HistoryEntry, bool, error) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	for _, entry := range history.entries {
+		if entry.ID == id {
+			return entry, true, nil
+		}
+	}
+	return HistoryEntry{}, false, nil
+}
+func (history *memoryHistory) List(name string, offset int, limit int) ([]HistoryEntry, int, error) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	entries := make([]HistoryEntry, 0)
+	total := 0
+	for i := len(history.entries) - 1; i >= 0; i-- {
+		if name != "" && history.entries[i].Suite != name {
+			continue
+		}
+		if total >= offset && len(entries) < limit {
+			entries = append(entries, history.entries[i])
+		}
+		total += 1
+	}
+	return entries, total, nil
+}
+
+// fileHistory keeps every run as a JSON file in a directory, indexed in
+// memory.
+type fileHistory struct {
+	memory *memoryHistory
+	dir    string
+}
+
+// NewFileHistory returns a HistoryStore keeping the retain most recent runs as
+// JSON files in dir, which is created if needed. Runs recorded by an earlier
+// process are loaded again.
+func NewFileHistory(dir string, retain int) (HistoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	history := &fileHistory{memory: &memoryHistory{entries: make([]HistoryEntry, 0), retain: retain}, dir: dir}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]HistoryEntry, 0)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		j, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(j, &entry); err != nil {
+			return nil, fmt.Errorf("invalid history file '%s': %s", file.Name(), err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].FinishedAt.Before(entries[j].FinishedAt)
+	})
+	for _, entry := range entries {
+		if err := history.evict(history.memory.record(entry)); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+func (history *fileHistory) Record(entry HistoryEntry) error {
+	j, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	temporary := history.path(entry.ID) + ".tmp"
+	if err := ioutil.WriteFile(temporary, j, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(temporary, history.path(entry.ID)); err != nil {
+		return err
+	}
+	return history.evict(history.memory.record(entry))
+}
+func (history *fileHistory) evict(entries []HistoryEntry) error {
+	for _, entry := range entries {
+		if err := os.Remove(history.path(entry.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+func (history *fileHistory) path(id string) string {
+	return filepath.Join(history.dir, id+".json")
+}
+func (history *fileHistory) Get(id string) (HistoryEntry, bool, error) {
+	return history.memory.Get(id)
+}
+func (history *fileHistory) List(name string, offset int, limit int) ([]HistoryEntry, int, error) {
+	return history.memory.List(name, offset, limit)
+}
+
+// WithHistory records every run in store instead of the in-memory history
+// keeping the last 1000 runs.
+func (api *Api) WithHistory(store HistoryStore) *Api {
+	api.runs.history = store
+	return api
+}
+
+// record adds a finished run of s to the history and the metrics and notifies
+// the webhooks about it.
+func (manager *runManager) record(id string, s suite.Suite, options suite.RunOptions, trigger string, result suite.Result) {
+	parameters := options.Parameters
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+	}
+	entry := HistoryEntry{
+		ID:         id,
+		Suite:      s.GetName(),
+		Parameters: parameters,
+		Select:     options.Select,
+		Cancelled:  options.Context != nil && options.Context.Err() != nil,
+		Trigger:    trigger,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.StartedAt.Add(result.Duration),
+		Result:     result,
+	}
+	previous, _ := lastCompleteRun(manager.history, entry.Suite)
+	if err := manager.history.Record(entry); err != nil {
+		fmt.Printf("HISTORY failed to record run '%s' of suite '%s': %s\n", id, s.GetName(), err.Error())
+	}
+	if !entry.Complete() {
+		return
+	}
+	manager.metrics.observe(entry.Suite, result)
+	manager.webhooks.notify(entry, previous)
+}
+
+// completeRuns calls visit with the recorded complete runs of the suite called
+// name, most recent first, until visit returns false.
+func completeRuns(history HistoryStore, name string, visit func(entry HistoryEntry) bool) error {
+	for offset := 0; ; offset += defaultHistoryPageSize {
+		entries, _, err := history.List(name, offset, defaultHistoryPageSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Complete() && !visit(entry) {
+				return nil
+			}
+		}
+		if len(entries) < defaultHistoryPageSize {
+			return nil
+		}
+	}
+}
+
+// lastCompleteRun returns the most recent complete run of the suite called
+// name, or nil if there is none.
+func lastCompleteRun(history HistoryStore, name string) (*HistoryEntry, error) {
+	var last *HistoryEntry
+	err := completeRuns(history, name, func(entry HistoryEntry) bool {
+		last = &entry
+		return false
+	})
+	return last, err
+}
+
+// createListHistoryHandler lists the recorded runs of the suites the sender
+// may list, most recent first. The suite query parameter restricts the list
+// to one suite, given by its endpoint; offset and limit select the page.
+func createListHistoryHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page := HistoryPage{Offset: 0, Limit: defaultHistoryPageSize}
+		for name, value := range map[string]*int{"offset": &page.Offset, "limit": &page.Limit} {
+			if text := r.URL.Query().Get(name); text != "" {
+				number, err := strconv.Atoi(text)
+				if err != nil || number < 0 {
+					writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid value '%s' for %s", text, name))
+					return
+				}
+				*value = number
+			}
+		}
+		if page.Limit > maxHistoryPageSize {
+			page.Limit = maxHistoryPageSize
+		}
+		name := ""
+		if endpoint := r.URL.Query().Get("suite"); endpoint != "" {
+			s, ok := api.findSuite(endpoint)
+			if !ok {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("No suite is served at '/%s'", endpoint))
+				return
+			}
+			if !api.authorize(r, ListAction, endpoint) {
+				forbidden(w, ListAction, endpoint)
+				return
+			}
+			name = s.GetName()
+		}
+		var err error
+		if name != "" || api.access.authorizer == nil {
+			page.Entries, page.Total, err = api.runs.history.List(name, page.Offset, page.Limit)
+		} else {
+			page.Entries, page.Total, err = api.listableHistory(r, page.Offset, page.Limit)
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read history with error: %s", err.Error()))
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// listableHistory pages through the recorded runs of the suites the sender of
+// r may list.
+func (api *Api) listableHistory(r *http.Request, offset int, limit int) ([]HistoryEntry, int, error) {
+	all, total, err := api.runs.history.List("", 0, math.MaxInt32)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]HistoryEntry, 0)
+	total = 0
+	for _, entry := range all {
+		if !api.allowed(r, ListAction, slugify(entry.Suite)) {
+			continue
+		}
+		if total >= offset && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+		total += 1
+	}
+	return entries, total, nil
+}
+func createGetHistoryHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		entry, ok, err := api.runs.history.Get(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read history with error: %s", err.Error()))
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No recorded run with id '%s'", id))
+			return
+		}
+		if !api.authorize(r, ListAction, slugify(entry.Suite)) {
+			forbidden(w, ListAction, slugify(entry.Suite))
+			return
+		}
+		writeJSON(w, http.StatusOK, entry)
+	}
+}
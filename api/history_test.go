@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHistoryEndpointsPageThroughRecordedRuns(t *testing.T) {
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("parent suite").
+			Parameter("tenant", suite.IntParameter, 1).
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			}),
+		suite.NewSequentialSuite("other suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			}),
+	}).WithHistory(NewMemoryHistory(3))
+
+	for i := 0; i < 3; i++ {
+		serve(t, api, http.MethodPost, fmt.Sprintf("/parent-suite?tenant=%d", i), nil, nil)
+	}
+	serve(t, api, http.MethodPost, "/other-suite", nil, nil)
+
+	var page HistoryPage
+	serve(t, api, http.MethodGet, "/history?limit=2", nil, &page)
+	if page.Total != 3 || len(page.Entries) != 2 || page.Entries[0].Suite != "other suite" {
+		t.Fatalf("expected 2 of 3 retained runs, most recent first, but got %v", page)
+	}
+	serve(t, api, http.MethodGet, "/history?suite=parent-suite&offset=1", nil, &page)
+	if page.Total != 2 || len(page.Entries) != 1 || page.Entries[0].Parameters["tenant"] != float64(1) || page.Entries[0].Trigger != EndpointTrigger {
+		t.Errorf("expected the oldest retained run of parent suite but got %v", page)
+	}
+
+	var entry HistoryEntry
+	if status := serve(t, api, http.MethodGet, "/history/"+page.Entries[0].ID, nil, &entry); status != http.StatusOK || entry.Result.TotalPassed != 1 {
+		t.Errorf("expected the recorded run but got %d %v", status, entry)
+	}
+	if status := serve(t, api, http.MethodGet, "/history/unknown", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run but got %d", status)
+	}
+}
+
+func TestFileHistoryKeepsRunsAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileHistory(dir, 2)
+	if err != nil {
+		t.Fatalf("expected file history but got %s", err.Error())
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		store.Record(HistoryEntry{ID: fmt.Sprintf("run-%d", i), Suite: "parent suite", FinishedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("expected 2 retained files but got %d", len(files))
+	}
+
+	store, err = NewFileHistory(dir, 2)
+	if err != nil {
+		t.Fatalf("expected file history to reopen but got %s", err.Error())
+	}
+	entries, total, _ := store.List("parent suite", 0, 10)
+	if total != 2 || entries[0].ID != "run-2" || entries[1].ID != "run-1" {
+		t.Errorf("expected run-2 and run-1 after reopening but got %v", entries)
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds in seconds of the duration histograms.
+var durationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// specStatuses are the values of the status label of the spec metrics.
+var specStatuses = []string{"passed", "failed", "skipped"}
+
+// histogram counts observed durations in durationBuckets.
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make([]uint64, len(durationBuckets))}
+}
+func (h *histogram) observe(duration time.Duration) {
+	seconds := duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i] += 1
+		}
+	}
+	h.count += 1
+	h.sum += seconds
+}
+
+// suiteMetrics are the metrics of a top-level suite.
+type suiteMetrics struct {
+	endpoint      string
+	inFlight      int
+	succeeded     uint64
+	failed        uint64
+	specs         map[string]uint64
+	lastSucceeded bool
+	lastRun       time.Time
+	duration      *histogram
+	specMetrics   map[string]*specMetrics
+}
+
+// specMetrics are the metrics of a spec of a top-level suite.
+type specMetrics struct {
+	statuses   map[string]uint64
+	lastStatus string
+	duration   *histogram
+}
+
+// metrics are derived from the runs of an Api and exposed at /metrics.
+type metrics struct {
+	mutex  sync.Mutex
+	suites map[string]*suiteMetrics
+}
+
+func newMetrics() *metrics {
+	return &metrics{suites: make(map[string]*suiteMetrics)}
+}
+
+// suite returns the metrics of the suite called name. It must be called with
+// the mutex held.
+func (m *metrics) suite(name string) *suiteMetrics {
+	metrics, ok := m.suites[name]
+	if !ok {
+		metrics = &suiteMetrics{
+			endpoint:    slugify(name),
+			specs:       make(map[string]uint64),
+			duration:    newHistogram(),
+			specMetrics: make(map[string]*specMetrics),
+		}
+		m.suites[name] = metrics
+	}
+	return metrics
+}
+
+// register exposes the metrics of the suite called name before its first run.
+func (m *metrics) register(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.suite(name)
+}
+
+// begin counts a run of the suite called name as in flight.
+func (m *metrics) begin(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.suite(name).inFlight += 1
+}
+
+// end counts a run of the suite called name as no longer in flight.
+func (m *metrics) end(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.suite(name).inFlight -= 1
+}
+
+// observe updates the metrics of the suite called name with a finished run.
+func (m *metrics) observe(name string, result suite.Result) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	metrics := m.suite(name)
+	metrics.lastSucceeded = result.Succeeded()
+	if metrics.lastSucceeded {
+		metrics.succeeded += 1
+	} else {
+		metrics.failed += 1
+	}
+	metrics.lastRun = result.StartedAt.Add(result.Duration)
+	metrics.duration.observe(result.Duration)
+	metrics.observeSpecs(result)
+}
+func (metrics *suiteMetrics) observeSpecs(result suite.Result) {
+	for _, specResult := range result.SpecResults {
+		status := strings.ToLower(specResult.Status)
+		spec, ok := metrics.specMetrics[specResult.Path]
+		if !ok {
+			spec = &specMetrics{statuses: make(map[string]uint64), duration: newHistogram()}
+			metrics.specMetrics[specResult.Path] = spec
+		}
+		metrics.specs[status] += 1
+		spec.statuses[status] += 1
+		spec.lastStatus = status
+		if status != "skipped" {
+			spec.duration.observe(specResult.Duration)
+		}
+	}
+	for _, child := range result.Children {
+		metrics.observeSpecs(child)
+	}
+}
+
+// metricsWriter writes metrics in the Prometheus text exposition format,
+// grouping the samples of each metric under its HELP and TYPE lines.
+type metricsWriter struct {
+	families map[string]*bytes.Buffer
+	order    []string
+}
+
+func newMetricsWriter() *metricsWriter {
+	return &metricsWriter{families: make(map[string]*bytes.Buffer), order: make([]string, 0)}
+}
+func (writer *metricsWriter) family(name string, kind string, help string) *bytes.Buffer {
+	family, ok := writer.families[name]
+	if !ok {
+		family = &bytes.Buffer{}
+		fmt.Fprintf(family, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		writer.families[name] = family
+		writer.order = append(writer.order, name)
+	}
+	return family
+}
+func (writer *metricsWriter) sample(name string, kind string, help string, labels []string, value float64) {
+	fmt.Fprintf(writer.family(name, kind, help), "%s%s %s\n", name, formatLabels(labels), formatValue(value))
+}
+func (writer *metricsWriter) histogram(name string, help string, labels []string, h *histogram) {
+	family := writer.family(name, "histogram", help)
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(family, "%s_bucket%s %d\n", name, formatLabels(append(labels, "le", formatValue(bound))), h.buckets[i])
+	}
+	fmt.Fprintf(family, "%s_bucket%s %d\n", name, formatLabels(append(labels, "le", "+Inf")), h.count)
+	fmt.Fprintf(family, "%s_sum%s %s\n", name, formatLabels(labels), formatValue(h.sum))
+	fmt.Fprintf(family, "%s_count%s %d\n", name, formatLabels(labels), h.count)
+}
+func (writer *metricsWriter) bytes() []byte {
+	var buffer bytes.Buffer
+	for _, name := range writer.order {
+		buffer.Write(writer.families[name].Bytes())
+	}
+	return buffer.Bytes()
+}
+
+// formatLabels formats pairs of label names and values.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// write writes the metrics of the suites for which include returns true.
+func (m *metrics) write(include func(endpoint string) bool) []byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	names := make([]string, 0, len(m.suites))
+	for name := range m.suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writer := newMetricsWriter()
+	for _, name := range names {
+		metrics := m.suites[name]
+		if !include(metrics.endpoint) {
+			continue
+		}
+		labels := []string{"suite", metrics.endpoint}
+		writer.sample("gopher_jasmine_suite_runs_in_flight", "gauge", "Runs of the suite that are queued or running.", labels, float64(metrics.inFlight))
+		writer.sample("gopher_jasmine_suite_runs_total", "counter", "Finished runs of the suite by outcome.", append(labels, "result", "succeeded"), float64(metrics.succeeded))
+		writer.sample("gopher_jasmine_suite_runs_total", "counter", "Finished runs of the suite by outcome.", append(labels, "result", "failed"), float64(metrics.failed))
+		if metrics.succeeded+metrics.failed == 0 {
+			continue
+		}
+		for _, status := range specStatuses {
+			writer.sample("gopher_jasmine_suite_specs_total", "counter", "Specs run by the suite by status.", append(labels, "status", status), float64(metrics.specs[status]))
+		}
+		writer.sample("gopher_jasmine_suite_last_success", "gauge", "Whether the last finished run of the suite succeeded.", labels, boolValue(metrics.lastSucceeded))
+		writer.sample("gopher_jasmine_suite_last_run_timestamp_seconds", "gauge", "Unix time the last run of the suite finished.", labels, float64(metrics.lastRun.UnixNano())/1e9)
+		writer.histogram("gopher_jasmine_suite_duration_seconds", "Duration of the runs of the suite.", labels, metrics.duration)
+		paths := make([]string, 0, len(metrics.specMetrics))
+		for path := range metrics.specMetrics {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			spec := metrics.specMetrics[path]
+			specLabels := []string{"suite", metrics.endpoint, "spec", path}
+			for _, status := range specStatuses {
+				writer.sample("gopher_jasmine_spec_runs_total", "counter", "Runs of the spec by status.", append(specLabels, "status", status), float64(spec.statuses[status]))
+			}
+			for _, status := range specStatuses {
+				writer.sample("gopher_jasmine_spec_last_status", "gauge", "1 for the status of the last run of the spec, 0 for the others.", append(specLabels, "status", status), boolValue(spec.lastStatus == status))
+			}
+			writer.histogram("gopher_jasmine_spec_duration_seconds", "Duration of the runs of the spec that were not skipped.", specLabels, spec.duration)
+		}
+	}
+	return writer.bytes()
+}
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// createMetricsHandler exposes the metrics of the suites the sender may list
+// in the Prometheus text exposition format.
+func createMetricsHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := api.runs.metrics.write(func(endpoint string) bool {
+			return api.allowed(r, ListAction, endpoint)
+		})
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsAreExposedInPrometheusFormat(t *testing.T) {
+	api := NewApi([]suite.Suite{
+		suite.NewSequentialSuite("parent suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			}).
+			It("returns \"json\"", func(instance map[string]interface{}) error {
+				return fmt.Errorf("expected json but got xml")
+			}),
+		suite.NewSequentialSuite("idle suite"),
+	})
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+	serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+
+	w := httptest.NewRecorder()
+	api.router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("expected 200 with the Prometheus content type but got %d and '%s'", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, line := range []string{
+		"# TYPE gopher_jasmine_suite_runs_total counter",
+		`gopher_jasmine_suite_runs_total{suite="parent-suite",result="failed"} 2`,
+		`gopher_jasmine_suite_runs_total{suite="parent-suite",result="succeeded"} 0`,
+		`gopher_jasmine_suite_runs_total{suite="idle-suite",result="failed"} 0`,
+		`gopher_jasmine_suite_runs_in_flight{suite="parent-suite"} 0`,
+		`gopher_jasmine_suite_specs_total{suite="parent-suite",status="passed"} 2`,
+		`gopher_jasmine_suite_last_success{suite="parent-suite"} 0`,
+		"# TYPE gopher_jasmine_suite_duration_seconds histogram",
+		`gopher_jasmine_suite_duration_seconds_bucket{suite="parent-suite",le="+Inf"} 2`,
+		`gopher_jasmine_suite_duration_seconds_count{suite="parent-suite"} 2`,
+		`gopher_jasmine_spec_runs_total{suite="parent-suite",spec="parent suite > returns \"json\"",status="failed"} 2`,
+		`gopher_jasmine_spec_last_status{suite="parent-suite",spec="parent suite > returns 200",status="passed"} 1`,
+		`gopher_jasmine_spec_last_status{suite="parent-suite",spec="parent suite > returns 200",status="failed"} 0`,
+		`gopher_jasmine_spec_duration_seconds_bucket{suite="parent-suite",spec="parent suite > returns 200",le="300"} 2`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected metrics to contain '%s' but got\n%s", line, body)
+		}
+	}
+	if strings.Count(string(body), "# TYPE gopher_jasmine_suite_runs_total") != 1 {
+		t.Errorf("expected every metric to be declared once but got\n%s", body)
+	}
+	if strings.Contains(string(body), `gopher_jasmine_suite_last_success{suite="idle-suite"}`) {
+		t.Errorf("expected no last result of a suite that never ran but got\n%s", body)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"strings"
+)
+
+// createNestedSuiteHandler runs a child suite or a single spec of s, addressed
+// by the endpoints of the suites leading to it and finally of the suite or
+// spec itself, e.g. /parent-suite/first-child-suite/returns-200, or by its
+// ID, e.g. /parent-suite/3f2a1b4c5d6e7f80. The hooks of every ancestor run
+// around it as they would in a run of the whole tree.
+func createNestedSuiteHandler(api *Api, s suite.Suite) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !api.runsOn(r) {
+			runRequiresPost(w, r)
+			return
+		}
+		segments := strings.Split(strings.Trim(mux.Vars(r)["path"], "/"), "/")
+		path, ok := resolvePath(s, segments)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No suite or spec at '%s' in suite '%s'", strings.Join(segments, "/"), s.GetName()))
+			return
+		}
+		api.runSuite(w, r, s, []string{suite.JoinPath(path)})
+	}
+}
+
+// resolvePath returns the path of the child suite or spec of s addressed by
+// segments, which are either the endpoints along the path or a single ID.
+func resolvePath(s suite.Suite, segments []string) ([]string, bool) {
+	path := []string{s.GetName()}
+	if len(segments) == 1 {
+		if found, ok := findID(s, path, segments[0]); ok {
+			return found, true
+		}
+	}
+	current := s
+	for i, segment := range segments {
+		child, ok := findChild(current, segment)
+		if ok {
+			path = append(path, child.GetName())
+			current = child
+			continue
+		}
+		if i != len(segments)-1 {
+			return nil, false
+		}
+		for _, spec := range suite.GetSpecs(current) {
+			if slugify(spec.Description) == segment {
+				return append(path, spec.Description), true
+			}
+		}
+		return nil, false
+	}
+	return path, true
+}
+func findChild(s suite.Suite, endpoint string) (suite.Suite, bool) {
+	for _, child := range suite.GetChildren(s) {
+		if slugify(child.Suite.GetName()) == endpoint {
+			return child.Suite, true
+		}
+	}
+	return nil, false
+}
+
+// findID returns the path of the child suite or spec of s at path with id.
+func findID(s suite.Suite, path []string, id string) ([]string, bool) {
+	for _, spec := range suite.GetSpecs(s) {
+		specPath := append(append(make([]string, 0, len(path)+1), path...), spec.Description)
+		if suite.PathID(specPath) == id {
+			return specPath, true
+		}
+	}
+	for _, child := range suite.GetChildren(s) {
+		childPath := append(append(make([]string, 0, len(path)+1), path...), child.Suite.GetName())
+		if suite.PathID(childPath) == id {
+			return childPath, true
+		}
+		if found, ok := findID(child.Suite, childPath, id); ok {
+			return found, true
+		}
+	}
+	return nil, false
+}
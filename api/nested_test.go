@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNestedRoutesRunChildSuitesAndSpecsWithAncestorHooks(t *testing.T) {
+	ran := make([]string, 0)
+	record := func(name string) func(instance map[string]interface{}) error {
+		return func(instance map[string]interface{}) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		BeforeAll("parent before all", record("parent before all")).
+		It("top level spec", record("top level spec")).
+		Describe(suite.NewSequentialSuite("first child suite").
+			It("returns 200", record("returns 200")).
+			It("returns 404", record("returns 404"))).
+		AfterAll("parent after all", record("parent after all"))})
+	run := func(target string) (int, suite.Result) {
+		recorder := httptest.NewRecorder()
+		api.router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+		var result suite.Result
+		json.Unmarshal(recorder.Body.Bytes(), &result)
+		return recorder.Code, result
+	}
+
+	if code, result := run("/parent-suite/first-child-suite"); code != http.StatusOK || result.TotalPassed != 2 || result.TotalSkipped != 1 {
+		t.Errorf("expected child suite to run but got %d with %d passed", code, result.TotalPassed)
+	}
+	expected := []string{"parent before all", "returns 200", "returns 404", "parent after all"}
+	if len(ran) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, ran)
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Errorf("expected %v but got %v", expected, ran)
+		}
+	}
+
+	ran = make([]string, 0)
+	id := suite.PathID([]string{"parent suite", "first child suite", "returns 404"})
+	if code, result := run("/parent-suite/" + id); code != http.StatusOK || result.TotalPassed != 1 {
+		t.Errorf("expected spec selected by id to run but got %d with %d passed", code, result.TotalPassed)
+	}
+	if code, result := run("/parent-suite/first-child-suite/returns-200"); code != http.StatusOK || result.TotalPassed != 1 {
+		t.Errorf("expected spec selected by path to run but got %d with %d passed", code, result.TotalPassed)
+	}
+	if code, _ := run("/parent-suite/second-child-suite"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown child suite but got %d", code)
+	}
+}
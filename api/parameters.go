@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// reservedQueryParameters configure the response of a suite endpoint and are
+// never taken for suite parameters.
+var reservedQueryParameters = []string{"select", "format", "tag", "concurrent"}
+
+// requestParameters resolves the parameters of a run of s from the query of r
+// and, if r carries a JSON object, from its body. An empty body carries no
+// parameters. Values of the body take
+// precedence over those of the query.
+func requestParameters(r *http.Request, s suite.Suite) (map[string]interface{}, error) {
+	values, err := requestParameterValues(r, s)
+	if err != nil {
+		return nil, err
+	}
+	return suite.ResolveParameters(s, values)
+}
+
+// requestParameterValues returns the unresolved parameter values of r. Query
+// parameters are only taken for parameters declared by one of suites, so that
+// cache busters and tracking parameters are ignored, while every value of the
+// body is returned for ResolveParameters to reject the undeclared ones.
+func requestParameterValues(r *http.Request, suites ...suite.Suite) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	query := r.URL.Query()
+	for _, s := range suites {
+		for _, parameter := range suite.GetParameters(s) {
+			if value, ok := query[parameter.Name]; ok && !isReservedQueryParameter(parameter.Name) {
+				values[parameter.Name] = value[len(value)-1]
+			}
+		}
+	}
+	if r.Body != nil && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		body := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Failed to read parameters with error: %s", err.Error())
+		}
+		for name, value := range body {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// declaredValues returns the values of values that are declared as
+// parameters in the suite tree of s.
+func declaredValues(s suite.Suite, values map[string]interface{}) map[string]interface{} {
+	declared := make(map[string]interface{})
+	for _, parameter := range suite.GetParameters(s) {
+		if value, ok := values[parameter.Name]; ok {
+			declared[parameter.Name] = value
+		}
+	}
+	return declared
+}
+func isReservedQueryParameter(name string) bool {
+	for _, reserved := range reservedQueryParameters {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
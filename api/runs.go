@@ -0,0 +1,499 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	QueuedRun    = "queued"
+	RunningRun   = "running"
+	CompletedRun = "completed"
+	CancelledRun = "cancelled"
+)
+
+// defaultRunRetention is the number of finished runs kept for retrieval
+// unless WithRunRetention says otherwise.
+const defaultRunRetention = 100
+
+// Run is a run of a suite started through the runs endpoint. While it is in
+// flight SpecResults holds the results of the specs finished so far; Result
+// is set once the run finished.
+type Run struct {
+	ID          string             `json:"id"`
+	Suite       string             `json:"suite"`
+	Status      string             `json:"status"`
+	StartedAt   time.Time          `json:"started_at"`
+	FinishedAt  *time.Time         `json:"finished_at"`
+	SpecResults []suite.SpecResult `json:"spec_results"`
+	Result      *suite.Result      `json:"result"`
+}
+
+// RunRequest is the body of a POST to the runs endpoint.
+type RunRequest struct {
+	Suite       string                 `json:"suite"`
+	Select      []string               `json:"select"`
+	FailFast    bool                   `json:"fail_fast"`
+	MaxFailures int                    `json:"max_failures"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+func (request RunRequest) options() suite.RunOptions {
+	return suite.RunOptions{
+		Select:      request.Select,
+		FailFast:    request.FailFast,
+		MaxFailures: request.MaxFailures,
+	}
+}
+
+type trackedRun struct {
+	run       Run
+	cancel    context.CancelFunc
+	cancelled bool
+	events    []suite.Event
+	changed   chan struct{}
+}
+
+// runManager runs suites in the background and keeps their runs. Runs of the
+// same suite share the suite's instance, so they are executed one at a time.
+type runManager struct {
+	mutex    sync.Mutex
+	runs     map[string]*trackedRun
+	order    []string
+	retain   int
+	locks    map[string]*sync.Mutex
+	inFlight sync.WaitGroup
+	ctx      context.Context
+	stop     context.CancelFunc
+	closed   bool
+	history  HistoryStore
+	webhooks *webhookNotifier
+	metrics  *metrics
+}
+
+// errShuttingDown is returned for runs requested after Shutdown was called.
+var errShuttingDown = fmt.Errorf("The server is shutting down")
+
+func newRunManager() *runManager {
+	ctx, stop := context.WithCancel(context.Background())
+	return &runManager{
+		runs:     make(map[string]*trackedRun),
+		order:    make([]string, 0),
+		retain:   defaultRunRetention,
+		locks:    make(map[string]*sync.Mutex),
+		ctx:      ctx,
+		stop:     stop,
+		history:  NewMemoryHistory(defaultHistoryRetention),
+		webhooks: newWebhookNotifier(),
+		metrics:  newMetrics(),
+	}
+}
+
+// admit counts a new run as in flight, unless the manager was closed.
+func (manager *runManager) admit() error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if manager.closed {
+		return errShuttingDown
+	}
+	manager.inFlight.Add(1)
+	return nil
+}
+
+// close makes the manager refuse new runs and returns a channel that is
+// closed once every run in flight finished.
+func (manager *runManager) close() <-chan struct{} {
+	manager.mutex.Lock()
+	manager.closed = true
+	manager.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		manager.inFlight.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// isClosed reports whether close was called.
+func (manager *runManager) isClosed() bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return manager.closed
+}
+
+// cancelAll cancels every run in flight, including those run synchronously
+// by runNow.
+func (manager *runManager) cancelAll() {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	for _, tracked := range manager.runs {
+		if tracked.run.FinishedAt == nil {
+			tracked.cancelled = true
+			tracked.run.Status = CancelledRun
+		}
+	}
+	manager.stop()
+}
+
+// lock returns the mutex serialising runs of the suite called name.
+func (manager *runManager) lock(name string) *sync.Mutex {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	lock, ok := manager.locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		manager.locks[name] = lock
+	}
+	return lock
+}
+
+// runNow runs s in the calling goroutine once no other run of it is active
+// and records it in the history.
+func (manager *runManager) runNow(s suite.Suite, options suite.RunOptions, trigger string) (suite.Result, error) {
+	if err := manager.admit(); err != nil {
+		return suite.Result{}, err
+	}
+	defer manager.inFlight.Done()
+	manager.metrics.begin(s.GetName())
+	defer manager.metrics.end(s.GetName())
+	if options.Context == nil {
+		options.Context = manager.ctx
+	}
+	lock := manager.lock(s.GetName())
+	lock.Lock()
+	defer lock.Unlock()
+	result := suite.RunWithOptions(s, options)
+	manager.record(newRunID(), s, options, trigger, result)
+	return result, nil
+}
+
+// start queues a run of s and returns it without waiting for it to finish.
+// Once finished, the run is recorded in the history.
+func (manager *runManager) start(s suite.Suite, options suite.RunOptions, trigger string) (Run, error) {
+	if err := manager.admit(); err != nil {
+		return Run{}, err
+	}
+	manager.metrics.begin(s.GetName())
+	ctx, cancel := context.WithCancel(manager.ctx)
+	tracked := &trackedRun{
+		run: Run{
+			ID:          newRunID(),
+			Suite:       s.GetName(),
+			Status:      QueuedRun,
+			StartedAt:   time.Now(),
+			SpecResults: make([]suite.SpecResult, 0),
+		},
+		cancel:  cancel,
+		events:  make([]suite.Event, 0),
+		changed: make(chan struct{}),
+	}
+	manager.mutex.Lock()
+	manager.runs[tracked.run.ID] = tracked
+	manager.order = append(manager.order, tracked.run.ID)
+	snapshot := tracked.snapshot()
+	manager.mutex.Unlock()
+
+	options.Context = ctx
+	listener := options.Listener
+	options.Listener = func(event suite.Event) {
+		manager.mutex.Lock()
+		if event.SpecResult != nil {
+			tracked.run.SpecResults = append(tracked.run.SpecResults, *event.SpecResult)
+		}
+		tracked.events = append(tracked.events, event)
+		tracked.notify()
+		manager.mutex.Unlock()
+		if listener != nil {
+			listener(event)
+		}
+	}
+	go func() {
+		defer manager.inFlight.Done()
+		defer manager.metrics.end(s.GetName())
+		defer cancel()
+		lock := manager.lock(s.GetName())
+		lock.Lock()
+		defer lock.Unlock()
+		manager.setStatus(tracked, RunningRun)
+		result := suite.RunWithOptions(s, options)
+		manager.record(tracked.run.ID, s, options, trigger, result)
+		manager.finish(tracked, result)
+	}()
+	return snapshot, nil
+}
+func (manager *runManager) setStatus(tracked *trackedRun, status string) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if !tracked.cancelled {
+		tracked.run.Status = status
+	}
+}
+func (manager *runManager) finish(tracked *trackedRun, result suite.Result) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	finishedAt := time.Now()
+	tracked.run.FinishedAt = &finishedAt
+	tracked.run.Result = &result
+	if tracked.cancelled {
+		tracked.run.Status = CancelledRun
+	} else {
+		tracked.run.Status = CompletedRun
+	}
+	tracked.notify()
+	manager.prune()
+}
+
+// notify wakes up everyone waiting for new events of the run. It must be
+// called with the mutex of the manager held.
+func (tracked *trackedRun) notify() {
+	close(tracked.changed)
+	tracked.changed = make(chan struct{})
+}
+
+// events returns the events of the run with id starting at index from, a
+// channel closed once there are more, and whether the run finished.
+func (manager *runManager) events(id string, from int) ([]suite.Event, <-chan struct{}, bool, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	tracked, ok := manager.runs[id]
+	if !ok {
+		return nil, nil, false, false
+	}
+	if from > len(tracked.events) {
+		from = len(tracked.events)
+	}
+	events := append(make([]suite.Event, 0), tracked.events[from:]...)
+	return events, tracked.changed, tracked.run.FinishedAt != nil, true
+}
+
+// prune forgets the oldest finished runs beyond the retention limit. It must
+// be called with the mutex held.
+func (manager *runManager) prune() {
+	finished := 0
+	for _, id := range manager.order {
+		if manager.runs[id].run.FinishedAt != nil {
+			finished += 1
+		}
+	}
+	order := make([]string, 0, len(manager.order))
+	for _, id := range manager.order {
+		if finished > manager.retain && manager.runs[id].run.FinishedAt != nil {
+			delete(manager.runs, id)
+			finished -= 1
+			continue
+		}
+		order = append(order, id)
+	}
+	manager.order = order
+}
+func (manager *runManager) get(id string) (Run, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	tracked, ok := manager.runs[id]
+	if !ok {
+		return Run{}, false
+	}
+	return tracked.snapshot(), true
+}
+
+// list returns the known runs, most recent first.
+func (manager *runManager) list() []Run {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	runs := make([]Run, 0, len(manager.order))
+	for i := len(manager.order) - 1; i >= 0; i-- {
+		runs = append(runs, manager.runs[manager.order[i]].snapshot())
+	}
+	return runs
+}
+
+// cancel cancels the run with id. Specs that already started finish, every
+// other spec of the run is reported as SKIPPED.
+func (manager *runManager) cancel(id string) (Run, int, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	tracked, ok := manager.runs[id]
+	if !ok {
+		return Run{}, http.StatusNotFound, fmt.Errorf("No run with id '%s'", id)
+	}
+	if tracked.run.FinishedAt != nil {
+		return tracked.snapshot(), http.StatusConflict, fmt.Errorf("Run '%s' already finished", id)
+	}
+	tracked.cancelled = true
+	tracked.run.Status = CancelledRun
+	tracked.cancel()
+	return tracked.snapshot(), http.StatusAccepted, nil
+}
+
+// snapshot copies the run so it can be read without holding the mutex.
+func (tracked *trackedRun) snapshot() Run {
+	run := tracked.run
+	run.SpecResults = append(make([]suite.SpecResult, 0, len(run.SpecResults)), run.SpecResults...)
+	return run
+}
+func newRunID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// startRun starts the run described by request in the background.
+func (api *Api) startRun(request RunRequest, trigger string) (Run, int, error) {
+	s, ok := api.findSuite(request.Suite)
+	if !ok {
+		return Run{}, http.StatusNotFound, fmt.Errorf("No suite is served at '/%s'", request.Suite)
+	}
+	parameters, err := suite.ResolveParameters(s, request.Parameters)
+	if err != nil {
+		return Run{}, http.StatusBadRequest, err
+	}
+	options := request.options()
+	options.Parameters = parameters
+	run, err := api.runs.start(s, options, trigger)
+	if err != nil {
+		return Run{}, http.StatusServiceUnavailable, err
+	}
+	return run, http.StatusAccepted, nil
+}
+
+func createStartRunHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request RunRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read run request with error: %s", err.Error()))
+			return
+		}
+		if !api.authorize(r, RunAction, request.Suite) {
+			forbidden(w, RunAction, request.Suite)
+			return
+		}
+		run, status, err := api.startRun(request, RunsTrigger)
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		writeJSON(w, status, run)
+	}
+}
+func createListRunsHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs := make([]Run, 0)
+		for _, run := range api.runs.list() {
+			if api.allowed(r, ListAction, slugify(run.Suite)) {
+				runs = append(runs, run)
+			}
+		}
+		writeJSON(w, http.StatusOK, runs)
+	}
+}
+func createGetRunHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		run, ok := api.runs.get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No run with id '%s'", id))
+			return
+		}
+		if !api.authorize(r, ListAction, slugify(run.Suite)) {
+			forbidden(w, ListAction, slugify(run.Suite))
+			return
+		}
+		writeJSON(w, http.StatusOK, run)
+	}
+}
+func createCancelRunHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if run, ok := api.runs.get(id); ok && !api.authorize(r, CancelAction, slugify(run.Suite)) {
+			forbidden(w, CancelAction, slugify(run.Suite))
+			return
+		}
+		run, status, err := api.runs.cancel(id)
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		writeJSON(w, status, run)
+	}
+}
+
+// follow calls emit with every event of the run with id from index from on,
+// waiting for new events until the run finished or ctx is done. It returns
+// false if there is no such run.
+func (manager *runManager) follow(ctx context.Context, id string, from int, emit func(index int, event suite.Event) error) bool {
+	events, changed, finished, ok := manager.events(id, from)
+	if !ok {
+		return false
+	}
+	next := from
+	for {
+		for _, event := range events {
+			if err := emit(next, event); err != nil {
+				return true
+			}
+			next += 1
+		}
+		if finished {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return true
+		}
+		events, changed, finished, ok = manager.events(id, next)
+		if !ok {
+			return true
+		}
+	}
+}
+
+// createRunEventsHandler streams the events of a run as Server-Sent Events.
+// Events that happened before the client connected are replayed first, or
+// those after Last-Event-ID when the client reconnects. The stream ends once
+// the run finished.
+func createRunEventsHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, "Streaming is not supported by this server")
+			return
+		}
+		from := 0
+		if lastEventID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+			from = lastEventID + 1
+		}
+		run, ok := api.runs.get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("No run with id '%s'", id))
+			return
+		}
+		if !api.authorize(r, ListAction, slugify(run.Suite)) {
+			forbidden(w, ListAction, slugify(run.Suite))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		api.runs.follow(r.Context(), id, from, func(index int, event suite.Event) error {
+			j, _ := json.Marshal(event)
+			_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", index, event.Type, j)
+			flusher.Flush()
+			return err
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, api *Api, method string, target string, body interface{}, response interface{}) int {
+	var reader *bytes.Reader
+	if body != nil {
+		j, _ := json.Marshal(body)
+		reader = bytes.NewReader(j)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	recorder := httptest.NewRecorder()
+	api.router().ServeHTTP(recorder, httptest.NewRequest(method, target, reader))
+	if response != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("expected json response from %s %s but got %s", method, target, recorder.Body.String())
+		}
+	}
+	return recorder.Code
+}
+func waitForRun(t *testing.T, api *Api, id string, done func(run Run) bool) Run {
+	var run Run
+	for i := 0; i < 200; i++ {
+		serve(t, api, http.MethodGet, "/runs/"+id, nil, &run)
+		if done(run) {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not reach the expected state, last seen %v", id, run)
+	return run
+}
+
+func TestRunsCanBePolledAndCancelled(t *testing.T) {
+	release := make(chan bool)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("1: should pass", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		It("2: should wait", func(instance map[string]interface{}) error {
+			<-release
+			return nil
+		}).
+		It("3: should not run after cancel", func(instance map[string]interface{}) error {
+			t.Errorf("expected cancelled run not to start further specs")
+			return nil
+		})})
+
+	var run Run
+	if status := serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, &run); status != http.StatusAccepted {
+		t.Fatalf("expected 202 but got %d", status)
+	}
+	run = waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.Status == RunningRun && len(run.SpecResults) == 1
+	})
+	if run.SpecResults[0].Status != "PASSED" {
+		t.Errorf("expected partial result of the first spec but got %v", run.SpecResults)
+	}
+
+	if status := serve(t, api, http.MethodDelete, "/runs/"+run.ID, nil, &run); status != http.StatusAccepted {
+		t.Errorf("expected 202 when cancelling but got %d", status)
+	}
+	release <- true
+	run = waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.Result != nil
+	})
+	if run.Status != CancelledRun {
+		t.Errorf("expected cancelled run but got %s", run.Status)
+	}
+	if run.Result.TotalPassed != 2 || run.Result.TotalSkipped != 1 {
+		t.Errorf("expected 2 passed and 1 skipped but got %d and %d", run.Result.TotalPassed, run.Result.TotalSkipped)
+	}
+	if status := serve(t, api, http.MethodDelete, "/runs/"+run.ID, nil, nil); status != http.StatusConflict {
+		t.Errorf("expected 409 when cancelling a finished run but got %d", status)
+	}
+
+	var runs []Run
+	serve(t, api, http.MethodGet, "/runs", nil, &runs)
+	if len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("expected the finished run to be retained but got %v", runs)
+	}
+}
+func TestRunsRejectUnknownSuite(t *testing.T) {
+	api := NewApi([]suite.Suite{})
+	if status := serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "unknown"}, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 but got %d", status)
+	}
+	if status := serve(t, api, http.MethodGet, "/runs/unknown", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 but got %d", status)
+	}
+}
+func TestRunEventsAreStreamedAsServerSentEvents(t *testing.T) {
+	release := make(chan bool)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("1: should wait", func(instance map[string]interface{}) error {
+			<-release
+			return nil
+		}).
+		It("2: should fail", func(instance map[string]interface{}) error {
+			return fmt.Errorf("exit 1")
+		}).
+		AfterAll("should fail", func(instance map[string]interface{}) error {
+			return fmt.Errorf("exit 1")
+		})})
+	server := httptest.NewServer(api.router())
+	defer server.Close()
+
+	var run Run
+	serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, &run)
+	response, err := http.Get(server.URL + "/runs/" + run.ID + "/events")
+	if err != nil {
+		t.Fatalf("expected event stream but got %s", err.Error())
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected text/event-stream but got %s", response.Header.Get("Content-Type"))
+	}
+	close(release)
+
+	types := make([]string, 0)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event: ") {
+			types = append(types, strings.TrimPrefix(scanner.Text(), "event: "))
+		}
+	}
+	expected := []string{
+		suite.SuiteStartedEvent,
+		suite.SpecPassedEvent,
+		suite.SpecFailedEvent,
+		suite.HookFailedEvent,
+		suite.SuiteFinishedEvent,
+		suite.RunFinishedEvent,
+	}
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Errorf("expected events %v but got %v", expected, types)
+	}
+}
+
+func TestShutdownCancelsRunsInFlightOnceContextIsDone(t *testing.T) {
+	release := make(chan bool)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("1: should wait", func(instance map[string]interface{}) error {
+			<-release
+			return nil
+		}).
+		It("2: should not run after shutdown", func(instance map[string]interface{}) error {
+			t.Errorf("expected shutdown not to start further specs")
+			return nil
+		})})
+
+	var run Run
+	serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, &run)
+	waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.Status == RunningRun
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	go func() {
+		waitForRun(t, api, run.ID, func(run Run) bool {
+			return run.Status == CancelledRun
+		})
+		release <- true
+	}()
+	api.Shutdown(ctx)
+
+	run, _ = api.runs.get(run.ID)
+	if run.Status != CancelledRun || run.Result == nil || run.Result.TotalSkipped != 1 {
+		t.Errorf("expected cancelled run with 1 skipped spec but got %v", run)
+	}
+	if status := serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after shutdown but got %d", status)
+	}
+}
+
+func TestShutdownDoesNotWaitForSpecsIgnoringCancellation(t *testing.T) {
+	release := make(chan bool)
+	defer close(release)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("ignores cancellation", func(instance map[string]interface{}) error {
+			<-release
+			return nil
+		})})
+
+	var run Run
+	serve(t, api, http.MethodPost, "/runs", RunRequest{Suite: "parent-suite"}, &run)
+	waitForRun(t, api, run.ID, func(run Run) bool {
+		return run.Status == RunningRun
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := api.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > shutdownGracePeriod+time.Second {
+		t.Errorf("expected shutdown to give up after the grace period but it took %s", elapsed)
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ScheduleTrigger is the trigger of runs started by a schedule.
+const ScheduleTrigger = "schedule"
+
+const InvalidScheduleProblem = "invalid_schedule"
+
+// ScheduleState describes a schedule registered with WithSchedule.
+type ScheduleState struct {
+	Suite         string                 `json:"suite"`
+	Spec          string                 `json:"spec"`
+	Parameters    map[string]interface{} `json:"parameters"`
+	Running       bool                   `json:"running"`
+	NextRun       *time.Time             `json:"next_run"`
+	LastStartedAt *time.Time             `json:"last_started_at"`
+	LastDuration  time.Duration          `json:"last_duration"`
+	LastSucceeded *bool                  `json:"last_succeeded"`
+	Runs          int                    `json:"runs"`
+	SkippedRuns   int                    `json:"skipped_runs"`
+}
+
+// schedule runs a suite whenever its cron expression fires, unless a run of
+// the suite started by a schedule is still running.
+type schedule struct {
+	suite   suite.Suite
+	cron    *cronSchedule
+	options suite.RunOptions
+	state   ScheduleState
+}
+
+// scheduler runs the schedules of an Api in the background between start and
+// stop.
+type scheduler struct {
+	mutex     sync.Mutex
+	schedules []*schedule
+	runs      *runManager
+	busy      map[string]bool
+	stopping  chan struct{}
+	stopped   sync.Once
+	// wait returns a channel that receives once next is reached and a function
+	// releasing it early. Tests replace it to fire schedules deterministically.
+	wait func(s *schedule, next time.Time) (<-chan time.Time, func() bool)
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		schedules: make([]*schedule, 0),
+		busy:      make(map[string]bool),
+		stopping:  make(chan struct{}),
+		wait:      waitUntil,
+	}
+}
+func waitUntil(s *schedule, next time.Time) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(time.Until(next))
+	return timer.C, timer.Stop
+}
+
+// WithSchedule runs the suite served at endpoint in the background whenever
+// spec fires, passing parameters. spec is a cron expression with the fields
+// minute, hour, day of month, month and day of week, or one of @yearly,
+// @monthly, @weekly, @daily, @hourly and @every <duration>, evaluated in
+// local time. A run is skipped if a run of the suite started by any schedule
+// is still in flight. Schedules start once the Api serves, see Handler, or
+// right away if it already does.
+func (api *Api) WithSchedule(endpoint string, spec string, parameters map[string]interface{}) *Api {
+	problem := func(message string) *Api {
+		api.problems = append(api.problems, suite.Problem{
+			Kind:    InvalidScheduleProblem,
+			Path:    endpoint,
+			Message: fmt.Sprintf("schedule '%s' of '/%s' %s", spec, endpoint, message),
+		})
+		fmt.Printf("INVALID Suite: %s\n", api.problems[len(api.problems)-1])
+		return api
+	}
+	s, ok := api.findSuite(endpoint)
+	if !ok {
+		return problem("does not address a suite")
+	}
+	cron, err := parseCron(spec)
+	if err != nil {
+		return problem(err.Error())
+	}
+	resolved, err := suite.ResolveParameters(s, parameters)
+	if err != nil {
+		return problem(err.Error())
+	}
+	api.scheduler.add(&schedule{
+		suite:   s,
+		cron:    cron,
+		options: suite.RunOptions{Parameters: resolved},
+		state:   ScheduleState{Suite: endpoint, Spec: spec, Parameters: resolved},
+	})
+	return api
+}
+
+// add registers s, starting it right away if the scheduler already started.
+func (scheduler *scheduler) add(s *schedule) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.schedules = append(scheduler.schedules, s)
+	if scheduler.runs != nil {
+		go scheduler.loop(scheduler.runs, s)
+	}
+}
+
+// start starts running the schedules, once.
+func (scheduler *scheduler) start(runs *runManager) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	if scheduler.runs != nil {
+		return
+	}
+	scheduler.runs = runs
+	for _, s := range scheduler.schedules {
+		go scheduler.loop(runs, s)
+	}
+}
+
+// stop stops starting new runs. Runs in flight are left to Shutdown.
+func (scheduler *scheduler) stop() {
+	scheduler.stopped.Do(func() {
+		close(scheduler.stopping)
+	})
+}
+func (scheduler *scheduler) loop(runs *runManager, s *schedule) {
+	for {
+		next := s.cron.next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		scheduler.mutex.Lock()
+		s.state.NextRun = &next
+		scheduler.mutex.Unlock()
+		fired, release := scheduler.wait(s, next)
+		select {
+		case <-scheduler.stopping:
+			release()
+			scheduler.mutex.Lock()
+			s.state.NextRun = nil
+			scheduler.mutex.Unlock()
+			return
+		case <-fired:
+		}
+		scheduler.mutex.Lock()
+		if scheduler.busy[s.suite.GetName()] {
+			s.state.SkippedRuns += 1
+			scheduler.mutex.Unlock()
+			fmt.Printf("SKIP Schedule '%s' of '/%s': previous scheduled run of the suite still in flight\n", s.state.Spec, s.state.Suite)
+			continue
+		}
+		scheduler.busy[s.suite.GetName()] = true
+		s.state.Running = true
+		scheduler.mutex.Unlock()
+		go scheduler.run(runs, s)
+	}
+}
+func (scheduler *scheduler) run(runs *runManager, s *schedule) {
+	startedAt := time.Now()
+	result, err := runs.runNow(s.suite, s.options, ScheduleTrigger)
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.busy[s.suite.GetName()] = false
+	s.state.Running = false
+	if err != nil {
+		return
+	}
+	succeeded := result.Succeeded()
+	s.state.Runs += 1
+	s.state.LastStartedAt = &startedAt
+	s.state.LastDuration = result.Duration
+	s.state.LastSucceeded = &succeeded
+}
+
+// states returns the state of every schedule.
+func (scheduler *scheduler) states() []ScheduleState {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	states := make([]ScheduleState, 0, len(scheduler.schedules))
+	for _, s := range scheduler.schedules {
+		states = append(states, s.state)
+	}
+	return states
+}
+
+// createListSchedulesHandler lists the schedules of the suites the sender may
+// list.
+func createListSchedulesHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		states := make([]ScheduleState, 0)
+		for _, state := range api.scheduler.states() {
+			if api.allowed(r, ListAction, state.Suite) {
+				states = append(states, state)
+			}
+		}
+		writeJSON(w, http.StatusOK, states)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCronExpressionsFireAtTheNextMatchingMinute(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	expectations := map[string]time.Time{
+		"*/15 * * * *":   time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC),
+		"0 9-17 * * 1-5": time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC),
+		"@daily":         time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":     time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"30 6 * * 0,6":   time.Date(2024, time.February, 3, 6, 30, 0, 0, time.UTC),
+		"@every 90s":     from.Add(90 * time.Second),
+	}
+	for spec, expected := range expectations {
+		cron, err := parseCron(spec)
+		if err != nil {
+			t.Errorf("expected '%s' to parse but got %s", spec, err.Error())
+			continue
+		}
+		if next := cron.next(from); !next.Equal(expected) {
+			t.Errorf("expected '%s' to fire at %s but got %s", spec, expected, next)
+		}
+	}
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "@every soon"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("expected '%s' to be rejected", spec)
+		}
+	}
+}
+
+func TestSchedulesRunInTheBackgroundWithoutOverlapping(t *testing.T) {
+	release := make(chan bool)
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			<-release
+			return nil
+		})}).
+		WithSchedule("parent-suite", "@hourly", nil)
+	ticks := map[string]chan time.Time{"@hourly": make(chan time.Time), "@daily": make(chan time.Time)}
+	api.scheduler.wait = func(s *schedule, next time.Time) (<-chan time.Time, func() bool) {
+		return ticks[s.state.Spec], func() bool { return true }
+	}
+
+	if problems := NewApi(nil).WithSchedule("unknown-suite", "@hourly", nil).Problems(); len(problems) != 1 || problems[0].Kind != InvalidScheduleProblem {
+		t.Errorf("expected schedule of unknown suite to be a problem but got %v", problems)
+	}
+	api.Handler()
+	api.WithSchedule("parent-suite", "@daily", nil)
+	ticks["@hourly"] <- time.Now()
+	waitForSchedules(t, api, func(states []ScheduleState) bool {
+		return states[0].Running
+	})
+	ticks["@daily"] <- time.Now()
+	ticks["@hourly"] <- time.Now()
+	waitForSchedules(t, api, func(states []ScheduleState) bool {
+		return states[0].SkippedRuns+states[1].SkippedRuns == 2
+	})
+	release <- true
+	states := waitForSchedules(t, api, func(states []ScheduleState) bool {
+		return states[0].Runs == 1
+	})
+	api.scheduler.stop()
+
+	if states[0].Runs != 1 || states[0].SkippedRuns != 1 || states[0].LastSucceeded == nil || !*states[0].LastSucceeded {
+		t.Errorf("expected one successful run and one skipped run but got %+v", states[0])
+	}
+	if states[1].Runs != 0 || states[1].SkippedRuns != 1 {
+		t.Errorf("expected the late schedule to start and skip the overlapping run of the suite but got %+v", states[1])
+	}
+	var page HistoryPage
+	serve(t, api, http.MethodGet, "/history", nil, &page)
+	if page.Total != 1 || page.Entries[0].Trigger != ScheduleTrigger {
+		t.Errorf("expected the scheduled run in the history but got %v", page)
+	}
+}
+func waitForSchedules(t *testing.T, api *Api, done func(states []ScheduleState) bool) []ScheduleState {
+	var states []ScheduleState
+	for i := 0; i < 200; i++ {
+		serve(t, api, http.MethodGet, "/schedules", nil, &states)
+		if done(states) {
+			return states
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("schedules did not reach the expected state, last seen %v", states)
+	return states
+}
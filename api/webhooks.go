@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Events webhooks are notified about.
+const (
+	// CompletedWebhookEvent is sent for every finished run.
+	CompletedWebhookEvent = "run.completed"
+	// FailedWebhookEvent is sent when a run of a suite fails after a run that
+	// succeeded, or as the first run of the suite.
+	FailedWebhookEvent = "run.failed"
+	// RecoveredWebhookEvent is sent when a run of a suite succeeds after a run
+	// that failed.
+	RecoveredWebhookEvent = "run.recovered"
+)
+
+// Headers of a webhook notification besides the configured ones.
+const (
+	SignatureHeader = "X-Gopher-Jasmine-Signature"
+	EventHeader     = "X-Gopher-Jasmine-Event"
+	DeliveryHeader  = "X-Gopher-Jasmine-Delivery"
+)
+
+const (
+	defaultWebhookAttempts   = 3
+	defaultWebhookBackoff    = time.Second
+	defaultWebhookTimeout    = 10 * time.Second
+	defaultDeliveryRetention = 100
+)
+
+// Webhook is an HTTP endpoint notified about finished runs with a POST of a
+// WebhookPayload.
+type Webhook struct {
+	URL string
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+	// Secret, if set, signs the body with HMAC-SHA256. The signature is sent
+	// as "sha256=<hex>" in the X-Gopher-Jasmine-Signature header.
+	Secret string
+	// Events restricts the notifications to these events. Empty means all.
+	Events []string
+	// Suites restricts the notifications to the suites served at these
+	// endpoints. Empty means all.
+	Suites []string
+	// MaxAttempts is the number of attempts to deliver a notification before
+	// giving up, 3 if zero.
+	MaxAttempts int
+	// Backoff is the delay before the second attempt, doubled for every
+	// further attempt, a second if zero.
+	Backoff time.Duration
+}
+
+// WebhookPayload is the body of a webhook notification.
+type WebhookPayload struct {
+	Event      string           `json:"event"`
+	RunID      string           `json:"run_id"`
+	Suite      string           `json:"suite"`
+	Trigger    string           `json:"trigger"`
+	Succeeded  bool             `json:"succeeded"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Duration   time.Duration    `json:"duration"`
+	Passed     int              `json:"passed"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	Failures   []WebhookFailure `json:"failures"`
+}
+
+// WebhookFailure is a failed spec or hook of a run.
+type WebhookFailure struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// WebhookDelivery records the attempts to deliver a notification.
+type WebhookDelivery struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	URL       string    `json:"url"`
+	Suite     string    `json:"suite"`
+	RunID     string    `json:"run_id"`
+	Time      time.Time `json:"time"`
+	Attempts  int       `json:"attempts"`
+	Status    int       `json:"status"`
+	Error     string    `json:"error"`
+	Delivered bool      `json:"delivered"`
+}
+
+// webhookNotifier sends the notifications of the webhooks of an Api and keeps
+// the log of their deliveries.
+type webhookNotifier struct {
+	mutex      sync.Mutex
+	webhooks   []Webhook
+	deliveries []WebhookDelivery
+	client     *http.Client
+	inFlight   sync.WaitGroup
+}
+
+func newWebhookNotifier() *webhookNotifier {
+	return &webhookNotifier{
+		webhooks:   make([]Webhook, 0),
+		deliveries: make([]WebhookDelivery, 0),
+		client:     &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+// WithWebhook notifies webhook about finished runs.
+func (api *Api) WithWebhook(webhook Webhook) *Api {
+	api.runs.webhooks.mutex.Lock()
+	defer api.runs.webhooks.mutex.Unlock()
+	api.runs.webhooks.webhooks = append(api.runs.webhooks.webhooks, webhook)
+	return api
+}
+
+// WebhookDeliveries returns the most recent webhook deliveries, oldest first.
+func (api *Api) WebhookDeliveries() []WebhookDelivery {
+	return api.runs.webhooks.log()
+}
+
+// notify sends the notifications about entry, the run following previous, or
+// the first run of its suite if there is no previous.
+func (notifier *webhookNotifier) notify(entry HistoryEntry, previous *HistoryEntry) {
+	succeeded := entry.Result.Succeeded()
+	events := []string{CompletedWebhookEvent}
+	if !succeeded && (previous == nil || previous.Result.Succeeded()) {
+		events = append(events, FailedWebhookEvent)
+	}
+	if succeeded && previous != nil && !previous.Result.Succeeded() {
+		events = append(events, RecoveredWebhookEvent)
+	}
+	notifier.mutex.Lock()
+	webhooks := append(make([]Webhook, 0, len(notifier.webhooks)), notifier.webhooks...)
+	notifier.mutex.Unlock()
+	for _, event := range events {
+		payload := newWebhookPayload(event, entry)
+		for _, webhook := range webhooks {
+			if (len(webhook.Events) == 0 || matches(webhook.Events, event)) && (len(webhook.Suites) == 0 || matches(webhook.Suites, slugify(entry.Suite))) {
+				notifier.inFlight.Add(1)
+				go notifier.deliver(webhook, payload)
+			}
+		}
+	}
+}
+func newWebhookPayload(event string, entry HistoryEntry) WebhookPayload {
+	return WebhookPayload{
+		Event:      event,
+		RunID:      entry.ID,
+		Suite:      entry.Suite,
+		Trigger:    entry.Trigger,
+		Succeeded:  entry.Result.Succeeded(),
+		StartedAt:  entry.StartedAt,
+		FinishedAt: entry.FinishedAt,
+		Duration:   entry.Result.Duration,
+		Passed:     entry.Result.TotalPassed,
+		Failed:     entry.Result.TotalFailed,
+		Skipped:    entry.Result.TotalSkipped,
+		Failures:   webhookFailures(entry.Result, make([]WebhookFailure, 0)),
+	}
+}
+func webhookFailures(result suite.Result, failures []WebhookFailure) []WebhookFailure {
+	for _, exception := range []*suite.ActionException{result.BeforeAllException, result.AfterAllException} {
+		if exception != nil {
+			failures = append(failures, WebhookFailure{Path: result.Path, Message: fmt.Sprintf("%s: %s", exception.Name, exception.Message)})
+		}
+	}
+	for _, exception := range result.CleanupExceptions {
+		failures = append(failures, WebhookFailure{Path: result.Path, Message: fmt.Sprintf("%s: %s", exception.Name, exception.Message)})
+	}
+	for _, specResult := range result.SpecResults {
+		if specResult.Status == "FAILED" {
+			failures = append(failures, WebhookFailure{Path: specResult.Path, Message: specResult.Message})
+		}
+		for _, exception := range []*suite.ActionException{specResult.BeforeEachException, specResult.AfterEachException} {
+			if exception != nil {
+				failures = append(failures, WebhookFailure{Path: specResult.Path, Message: fmt.Sprintf("%s: %s", exception.Name, exception.Message)})
+			}
+		}
+		for _, exception := range specResult.CleanupExceptions {
+			failures = append(failures, WebhookFailure{Path: specResult.Path, Message: fmt.Sprintf("%s: %s", exception.Name, exception.Message)})
+		}
+	}
+	for _, child := range result.Children {
+		failures = webhookFailures(child, failures)
+	}
+	return failures
+}
+
+// deliver posts payload to webhook, retrying with exponential backoff until
+// it is answered with a 2xx status or the attempts are used up.
+func (notifier *webhookNotifier) deliver(webhook Webhook, payload WebhookPayload) {
+	defer notifier.inFlight.Done()
+	delivery := WebhookDelivery{ID: newRunID(), Event: payload.Event, URL: webhook.URL, Suite: payload.Suite, RunID: payload.RunID, Time: time.Now()}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		notifier.logDelivery(delivery)
+		return
+	}
+	attempts := webhook.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	backoff := webhook.Backoff
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+	for delivery.Attempts < attempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		delivery.Attempts += 1
+		delivery.Status, err = notifier.post(webhook, delivery.ID, payload.Event, body)
+		if err == nil {
+			delivery.Error = ""
+			delivery.Delivered = true
+			break
+		}
+		delivery.Error = err.Error()
+	}
+	if !delivery.Delivered {
+		fmt.Printf("WEBHOOK failed to deliver '%s' of run '%s' to %s after %d attempts: %s\n", delivery.Event, delivery.RunID, delivery.URL, delivery.Attempts, delivery.Error)
+	}
+	notifier.logDelivery(delivery)
+}
+func (notifier *webhookNotifier) post(webhook Webhook, id string, event string, body []byte) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range webhook.Headers {
+		request.Header.Set(name, value)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, event)
+	request.Header.Set(DeliveryHeader, id)
+	if webhook.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// Sign returns the signature of a webhook body as sent in the
+// X-Gopher-Jasmine-Signature header, for verifying notifications.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+func (notifier *webhookNotifier) logDelivery(delivery WebhookDelivery) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	notifier.deliveries = append(notifier.deliveries, delivery)
+	if len(notifier.deliveries) > defaultDeliveryRetention {
+		notifier.deliveries = notifier.deliveries[len(notifier.deliveries)-defaultDeliveryRetention:]
+	}
+}
+
+// drained returns a channel that is closed once every delivery in flight
+// finished.
+func (notifier *webhookNotifier) drained() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		notifier.inFlight.Wait()
+		close(done)
+	}()
+	return done
+}
+func (notifier *webhookNotifier) log() []WebhookDelivery {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	return append(make([]WebhookDelivery, 0, len(notifier.deliveries)), notifier.deliveries...)
+}
+
+// createListDeliveriesHandler lists the webhook deliveries about suites the
+// sender may list, most recent first.
+func createListDeliveriesHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := api.runs.webhooks.log()
+		deliveries := make([]WebhookDelivery, 0, len(log))
+		for i := len(log) - 1; i >= 0; i-- {
+			if api.allowed(r, ListAction, slugify(log[i].Suite)) {
+				deliveries = append(deliveries, log[i])
+			}
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	}
+}
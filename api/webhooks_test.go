@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhooksAreNotifiedAboutFailuresAndRecoveries(t *testing.T) {
+	var mutex sync.Mutex
+	attempts := make(map[string]int)
+	received := make([]WebhookPayload, 0)
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Token") != "token" || r.Header.Get(SignatureHeader) != Sign("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		delivery := r.Header.Get(DeliveryHeader)
+		attempts[delivery] += 1
+		if attempts[delivery] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload WebhookPayload
+		json.Unmarshal(body, &payload)
+		received = append(received, payload)
+	}))
+	defer standIn.Close()
+
+	failing := true
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			if failing {
+				return fmt.Errorf("expected 200 but got 500")
+			}
+			return nil
+		})}).
+		WithWebhook(Webhook{URL: standIn.URL, Headers: map[string]string{"X-Token": "token"}, Secret: "secret", Backoff: time.Millisecond}).
+		WithWebhook(Webhook{URL: standIn.URL, Secret: "wrong", Events: []string{RecoveredWebhookEvent}, MaxAttempts: 2, Backoff: time.Millisecond})
+
+	for _, fail := range []bool{true, true, false} {
+		failing = fail
+		serve(t, api, http.MethodPost, "/parent-suite", nil, nil)
+	}
+	<-api.runs.webhooks.drained()
+
+	events := make([]string, 0)
+	for _, payload := range received {
+		events = append(events, payload.Event)
+	}
+	sort.Strings(events)
+	expected := fmt.Sprint([]string{CompletedWebhookEvent, CompletedWebhookEvent, CompletedWebhookEvent, FailedWebhookEvent, RecoveredWebhookEvent})
+	if fmt.Sprint(events) != expected {
+		t.Errorf("expected events %s but got %v", expected, events)
+	}
+	for _, payload := range received {
+		if payload.Event == FailedWebhookEvent && (payload.Failed != 1 || len(payload.Failures) != 1 || payload.Failures[0].Message != "expected 200 but got 500") {
+			t.Errorf("expected the failure in the payload but got %+v", payload)
+		}
+		if payload.Event == RecoveredWebhookEvent && (!payload.Succeeded || payload.Passed != 1 || payload.Trigger != EndpointTrigger) {
+			t.Errorf("expected a successful run in the payload but got %+v", payload)
+		}
+	}
+
+	deliveries := api.WebhookDeliveries()
+	if len(deliveries) != 6 {
+		t.Fatalf("expected 6 deliveries but got %v", deliveries)
+	}
+	for _, delivery := range deliveries {
+		if delivery.Event == RecoveredWebhookEvent && delivery.URL == standIn.URL && !delivery.Delivered && (delivery.Attempts != 2 || delivery.Status != http.StatusUnauthorized) {
+			t.Errorf("expected the wrongly signed delivery to fail after 2 attempts but got %+v", delivery)
+		}
+		if delivery.Delivered && delivery.Attempts != 2 {
+			t.Errorf("expected delivery to succeed on the second attempt but got %+v", delivery)
+		}
+	}
+	var listed []WebhookDelivery
+	serve(t, api, http.MethodGet, "/webhooks/deliveries", nil, &listed)
+	if len(listed) != 6 || listed[0].ID != deliveries[5].ID {
+		t.Errorf("expected the deliveries most recent first but got %v", listed)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"sync"
+)
+
+// Messages sent by clients over the WebSocket endpoint.
+const (
+	// ListMessage asks for the suites served by the Api.
+	ListMessage = "list"
+	// StartMessage starts the run described by Run and subscribes to it.
+	StartMessage = "start"
+	// SubscribeMessage subscribes to the events of the run RunID.
+	SubscribeMessage = "subscribe"
+	// CancelMessage cancels the run RunID.
+	CancelMessage = "cancel"
+)
+
+// Messages sent by the server over the WebSocket endpoint.
+const (
+	// SuitesMessage answers ListMessage with Suites.
+	SuitesMessage = "suites"
+	// StartedMessage answers StartMessage with the queued Run.
+	StartedMessage = "started"
+	// EventMessage carries an Event of the subscribed run RunID. The last
+	// event of a run is a suite.RunFinishedEvent.
+	EventMessage = "event"
+	// CancelledMessage answers CancelMessage with the cancelled Run.
+	CancelledMessage = "cancelled"
+	// ErrorMessage reports a request that failed, with Status and Message.
+	ErrorMessage = "error"
+)
+
+// ClientMessage is a JSON message sent by a client to the WebSocket endpoint.
+// ID is optional and echoed back in the answer so that clients can correlate
+// requests and answers.
+type ClientMessage struct {
+	Type  string      `json:"type"`
+	ID    string      `json:"id,omitempty"`
+	RunID string      `json:"run_id,omitempty"`
+	Run   *RunRequest `json:"run,omitempty"`
+}
+
+// ServerMessage is a JSON message sent by the WebSocket endpoint.
+type ServerMessage struct {
+	Type    string       `json:"type"`
+	ID      string       `json:"id,omitempty"`
+	RunID   string       `json:"run_id,omitempty"`
+	Suites  []SuiteInfo  `json:"suites,omitempty"`
+	Run     *Run         `json:"run,omitempty"`
+	Event   *suite.Event `json:"event,omitempty"`
+	Status  int          `json:"status,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// SuiteInfo describes a top-level suite served by the Api.
+type SuiteInfo struct {
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	Tags     []string `json:"tags"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// webSocketConnection serialises the writes of the goroutines forwarding
+// events to one client.
+type webSocketConnection struct {
+	mutex sync.Mutex
+	conn  *websocket.Conn
+}
+
+func (connection *webSocketConnection) send(message ServerMessage) error {
+	connection.mutex.Lock()
+	defer connection.mutex.Unlock()
+	return connection.conn.WriteJSON(message)
+}
+
+// createWebSocketHandler serves the interactive control channel. Clients send
+// ClientMessages and receive ServerMessages; see the README for the protocol.
+func createWebSocketHandler(api *Api) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		connection := &webSocketConnection{conn: conn}
+		for {
+			// A failed read means the connection is gone, only malformed
+			// messages are answered with an error.
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message ClientMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				if connection.send(ServerMessage{Type: ErrorMessage, Status: http.StatusBadRequest, Message: err.Error()}) != nil {
+					return
+				}
+				continue
+			}
+			api.handleClientMessage(ctx, r, connection, message)
+		}
+	}
+}
+
+// handleClientMessage answers message, authorizing it as if it was sent by
+// the request r that opened the connection.
+func (api *Api) handleClientMessage(ctx context.Context, r *http.Request, connection *webSocketConnection, message ClientMessage) {
+	fail := func(status int, text string) {
+		connection.send(ServerMessage{Type: ErrorMessage, ID: message.ID, RunID: message.RunID, Status: status, Message: text})
+	}
+	authorizeRun := func(action string) bool {
+		run, ok := api.runs.get(message.RunID)
+		if !ok {
+			fail(http.StatusNotFound, "No run with id '"+message.RunID+"'")
+			return false
+		}
+		if !api.authorize(r, action, slugify(run.Suite)) {
+			fail(http.StatusForbidden, forbiddenMessage(action, slugify(run.Suite)))
+			return false
+		}
+		return true
+	}
+	switch message.Type {
+	case ListMessage:
+		connection.send(ServerMessage{Type: SuitesMessage, ID: message.ID, Suites: api.suiteInfos(r)})
+	case StartMessage:
+		if message.Run == nil {
+			fail(http.StatusBadRequest, "Message 'start' requires a run")
+			return
+		}
+		if !api.authorize(r, RunAction, message.Run.Suite) {
+			fail(http.StatusForbidden, forbiddenMessage(RunAction, message.Run.Suite))
+			return
+		}
+		run, status, err := api.startRun(*message.Run, WebSocketTrigger)
+		if err != nil {
+			fail(status, err.Error())
+			return
+		}
+		connection.send(ServerMessage{Type: StartedMessage, ID: message.ID, RunID: run.ID, Run: &run})
+		go api.forwardEvents(ctx, connection, run.ID)
+	case SubscribeMessage:
+		if !authorizeRun(ListAction) {
+			return
+		}
+		go api.forwardEvents(ctx, connection, message.RunID)
+	case CancelMessage:
+		if !authorizeRun(CancelAction) {
+			return
+		}
+		run, status, err := api.runs.cancel(message.RunID)
+		if err != nil {
+			fail(status, err.Error())
+			return
+		}
+		connection.send(ServerMessage{Type: CancelledMessage, ID: message.ID, RunID: run.ID, Run: &run})
+	default:
+		fail(http.StatusBadRequest, "Unknown message type '"+message.Type+"'")
+	}
+}
+func (api *Api) forwardEvents(ctx context.Context, connection *webSocketConnection, id string) {
+	api.runs.follow(ctx, id, 0, func(index int, event suite.Event) error {
+		return connection.send(ServerMessage{Type: EventMessage, RunID: id, Event: &event})
+	})
+}
+
+// suiteInfos describes the top-level suites the sender of r may list.
+func (api *Api) suiteInfos(r *http.Request) []SuiteInfo {
+	infos := make([]SuiteInfo, 0)
+	for _, s := range api.listable(r) {
+		tags := suite.GetTags(s)
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		infos = append(infos, SuiteInfo{Name: s.GetName(), Endpoint: slugify(s.GetName()), Tags: tags})
+	}
+	return infos
+}
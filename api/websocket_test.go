@@ -0,0 +1,101 @@
+package api
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebSocketListsStartsAndStreamsRuns(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		Tag("smoke").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})})
+	server := httptest.NewServer(api.router())
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("expected websocket connection but got %s", err.Error())
+	}
+	defer conn.Close()
+
+	var message ServerMessage
+	conn.WriteJSON(ClientMessage{Type: ListMessage, ID: "1"})
+	conn.ReadJSON(&message)
+	if message.Type != SuitesMessage || message.ID != "1" || len(message.Suites) != 1 || message.Suites[0].Endpoint != "parent-suite" {
+		t.Errorf("expected the served suites but got %v", message)
+	}
+
+	conn.WriteJSON(ClientMessage{Type: StartMessage, ID: "2", Run: &RunRequest{Suite: "parent-suite"}})
+	conn.ReadJSON(&message)
+	if message.Type != StartedMessage || message.Run == nil {
+		t.Fatalf("expected the started run but got %v", message)
+	}
+	runID := message.RunID
+	types := make([]string, 0)
+	for message.Type != EventMessage || message.Event.Type != suite.RunFinishedEvent {
+		message = ServerMessage{}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("expected events but got %s", err.Error())
+		}
+		if message.RunID != runID {
+			t.Errorf("expected events of run %s but got %v", runID, message)
+		}
+		types = append(types, message.Event.Type)
+	}
+	if len(types) != 4 || types[1] != suite.SpecPassedEvent {
+		t.Errorf("expected the events of the run but got %v", types)
+	}
+
+	conn.WriteJSON(ClientMessage{Type: CancelMessage, ID: "3", RunID: runID})
+	conn.ReadJSON(&message)
+	if message.Type != ErrorMessage || message.Status != http.StatusConflict || message.ID != "3" {
+		t.Errorf("expected an error when cancelling a finished run but got %v", message)
+	}
+}
+
+func TestWebSocketAnswersMalformedMessagesAndStopsOnBrokenConnections(t *testing.T) {
+	api := NewApi([]suite.Suite{suite.NewSequentialSuite("parent suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})})
+	stopped := make(chan bool)
+	handler := createWebSocketHandler(api)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r)
+		close(stopped)
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("expected websocket connection but got %s", err.Error())
+	}
+	defer conn.Close()
+
+	var message ServerMessage
+	for _, text := range []string{`{"type": `, `{"type": 7}`} {
+		conn.WriteMessage(websocket.TextMessage, []byte(text))
+		message = ServerMessage{}
+		conn.ReadJSON(&message)
+		if message.Type != ErrorMessage || message.Status != http.StatusBadRequest {
+			t.Errorf("expected an error for '%s' but got %v", text, message)
+		}
+	}
+	conn.WriteJSON(ClientMessage{Type: ListMessage, ID: "1"})
+	conn.ReadJSON(&message)
+	if message.Type != SuitesMessage {
+		t.Errorf("expected the connection to survive malformed messages but got %v", message)
+	}
+
+	conn.UnderlyingConn().Close()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the handler to stop once the connection broke")
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/hyperstripe50/gopher-jasmine
 
 go 1.15
+
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
+)
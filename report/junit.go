@@ -0,0 +1,151 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	ID        string           `xml:"id,attr,omitempty"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase  `xml:"testcase"`
+	Suites    []junitTestSuite `xml:"testsuite"`
+}
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	File      string         `xml:"file,attr,omitempty"`
+	Line      int            `xml:"line,attr,omitempty"`
+	Failure   *junitProblem  `xml:"failure"`
+	Errors    []junitProblem `xml:"error"`
+	Skipped   *junitSkipped  `xml:"skipped"`
+}
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// JUnit serialises result as JUnit XML. Child suites become nested
+// testsuite elements and specs become testcases with failure or skipped
+// elements. Exceptions of BeforeEach, AfterEach and cleanups are reported as
+// errors of the spec, those of BeforeAll, AfterAll and suite cleanups as
+// errors of an extra testcase named after the hook.
+func JUnit(result suite.Result) ([]byte, error) {
+	testSuite := junitSuite(result)
+	document := junitTestSuites{
+		Name:     result.Name,
+		Tests:    testSuite.Tests,
+		Failures: testSuite.Failures,
+		Errors:   testSuite.Errors,
+		Skipped:  testSuite.Skipped,
+		Time:     seconds(result.Duration),
+		Suites:   []junitTestSuite{testSuite},
+	}
+	j, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(j, '\n')...), nil
+}
+
+func junitSuite(result suite.Result) junitTestSuite {
+	testSuite := junitTestSuite{
+		Name:      result.Name,
+		ID:        result.ID,
+		Time:      seconds(result.Duration),
+		TestCases: make([]junitTestCase, 0),
+		Suites:    make([]junitTestSuite, 0),
+	}
+	if !result.StartedAt.IsZero() {
+		testSuite.Timestamp = result.StartedAt.Format(time.RFC3339)
+	}
+	className := result.Path
+	if className == "" {
+		className = result.Name
+	}
+	for _, hook := range suiteHookExceptions(result) {
+		testSuite.TestCases = append(testSuite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s: %s", hook.Kind, hook.Exception.Name),
+			ClassName: className,
+			Time:      seconds(0),
+			File:      hook.Exception.Location.File,
+			Line:      hook.Exception.Location.Line,
+			Errors:    []junitProblem{junitError(hook)},
+		})
+	}
+	for _, specResult := range result.SpecResults {
+		testCase := junitTestCase{
+			Name:      specResult.Name,
+			ClassName: className,
+			Time:      seconds(specResult.Duration),
+			File:      specResult.Location.File,
+			Line:      specResult.Location.Line,
+		}
+		switch specResult.Status {
+		case "FAILED":
+			testCase.Failure = &junitProblem{
+				Message: specResult.Message,
+				Type:    specResult.Status,
+				Text:    failureText(specResult),
+			}
+		case "SKIPPED":
+			testCase.Skipped = &junitSkipped{Message: specResult.Message}
+		}
+		for _, hook := range specHookExceptions(specResult) {
+			testCase.Errors = append(testCase.Errors, junitError(hook))
+		}
+		testSuite.TestCases = append(testSuite.TestCases, testCase)
+	}
+	for _, testCase := range testSuite.TestCases {
+		testSuite.Tests += 1
+		if testCase.Failure != nil {
+			testSuite.Failures += 1
+		}
+		if testCase.Skipped != nil {
+			testSuite.Skipped += 1
+		}
+		testSuite.Errors += len(testCase.Errors)
+	}
+	for _, child := range result.Children {
+		childSuite := junitSuite(child)
+		testSuite.Tests += childSuite.Tests
+		testSuite.Failures += childSuite.Failures
+		testSuite.Errors += childSuite.Errors
+		testSuite.Skipped += childSuite.Skipped
+		testSuite.Suites = append(testSuite.Suites, childSuite)
+	}
+	return testSuite
+}
+func junitError(hook hookException) junitProblem {
+	return junitProblem{
+		Message: hook.Exception.Message,
+		Type:    hook.Kind,
+		Text:    exceptionText(hook),
+	}
+}
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"strings"
+	"testing"
+)
+
+func exampleResult() suite.Result {
+	return suite.NewSequentialSuite("parent suite").
+		It("should pass", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		It("should fail", func(instance map[string]interface{}) error {
+			return suite.Errorf("expected 200 but got %d", 500)
+		}).
+		XIt("should skip", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		Describe(suite.NewSequentialSuite("first child suite").
+			BeforeAll("should fail", func(instance map[string]interface{}) error {
+				return fmt.Errorf("setup failed")
+			}).
+			It("should be skipped", func(instance map[string]interface{}) error {
+				return nil
+			})).
+		AfterAll("should fail", func(instance map[string]interface{}) error {
+			return fmt.Errorf("teardown failed")
+		}).Run()
+}
+
+func TestJUnitNestsSuitesAndReportsHookExceptionsAsErrors(t *testing.T) {
+	body, err := JUnit(exampleResult())
+	if err != nil {
+		t.Fatalf("expected junit xml but got %s", err.Error())
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Errorf("expected xml header")
+	}
+	var document junitTestSuites
+	if err := xml.Unmarshal(body, &document); err != nil {
+		t.Fatalf("expected valid xml but got %s", err.Error())
+	}
+	if document.Tests != 6 || document.Failures != 1 || document.Errors != 2 || document.Skipped != 2 {
+		t.Errorf("expected 6 tests, 1 failure, 2 errors and 2 skipped but got %d, %d, %d and %d", document.Tests, document.Failures, document.Errors, document.Skipped)
+	}
+	parent := document.Suites[0]
+	if len(parent.Suites) != 1 || parent.Suites[0].Name != "first child suite" {
+		t.Fatalf("expected nested child suite but got %v", parent.Suites)
+	}
+	if parent.TestCases[0].Errors[0].Type != suite.AfterAllHook {
+		t.Errorf("expected after all exception as error but got %v", parent.TestCases[0])
+	}
+	failed := parent.TestCases[2]
+	if failed.Failure == nil || failed.Failure.Message != "expected 200 but got 500" || !strings.Contains(failed.Failure.Text, "junit_test.go") {
+		t.Errorf("expected failure with location but got %v", failed.Failure)
+	}
+	if parent.TestCases[3].Skipped == nil {
+		t.Errorf("expected skipped element but got %v", parent.TestCases[3])
+	}
+	if parent.Suites[0].TestCases[0].ClassName != "parent suite > first child suite" {
+		t.Errorf("expected class name to be the suite path but got %s", parent.Suites[0].TestCases[0].ClassName)
+	}
+}
@@ -0,0 +1,66 @@
+// Package report serialises the results of a suite run for other tools and
+// for humans.
+package report
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"strings"
+)
+
+// hookException is an exception raised by a hook or cleanup of a suite or
+// spec.
+type hookException struct {
+	Kind      string
+	Exception *suite.ActionException
+}
+
+func suiteHookExceptions(result suite.Result) []hookException {
+	hooks := make([]hookException, 0)
+	if result.BeforeAllException != nil {
+		hooks = append(hooks, hookException{Kind: suite.BeforeAllHook, Exception: result.BeforeAllException})
+	}
+	if result.AfterAllException != nil {
+		hooks = append(hooks, hookException{Kind: suite.AfterAllHook, Exception: result.AfterAllException})
+	}
+	for i := range result.CleanupExceptions {
+		hooks = append(hooks, hookException{Kind: suite.CleanupHook, Exception: &result.CleanupExceptions[i]})
+	}
+	return hooks
+}
+func specHookExceptions(specResult suite.SpecResult) []hookException {
+	hooks := make([]hookException, 0)
+	if specResult.BeforeEachException != nil {
+		hooks = append(hooks, hookException{Kind: suite.BeforeEachHook, Exception: specResult.BeforeEachException})
+	}
+	if specResult.AfterEachException != nil {
+		hooks = append(hooks, hookException{Kind: suite.AfterEachHook, Exception: specResult.AfterEachException})
+	}
+	for i := range specResult.CleanupExceptions {
+		hooks = append(hooks, hookException{Kind: suite.CleanupHook, Exception: &specResult.CleanupExceptions[i]})
+	}
+	return hooks
+}
+
+// failureText describes a failed spec with the locations it was declared at
+// and failed at, when they are known.
+func failureText(specResult suite.SpecResult) string {
+	lines := []string{specResult.Message}
+	if specResult.FailureLocation != nil {
+		lines = append(lines, fmt.Sprintf("at %s", specResult.FailureLocation))
+	}
+	if specResult.Location.File != "" {
+		lines = append(lines, fmt.Sprintf("declared at %s", specResult.Location))
+	}
+	return strings.Join(lines, "\n")
+}
+func exceptionText(hook hookException) string {
+	lines := []string{fmt.Sprintf("%s '%s': %s", hook.Kind, hook.Exception.Name, hook.Exception.Message)}
+	if hook.Exception.FailureLocation != nil {
+		lines = append(lines, fmt.Sprintf("at %s", hook.Exception.FailureLocation))
+	}
+	if hook.Exception.Location.File != "" {
+		lines = append(lines, fmt.Sprintf("declared at %s", hook.Exception.Location))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"strings"
+)
+
+// TAP serialises result in the Test Anything Protocol, version 14. Each child
+// suite becomes an indented subtest whose own plan and test points precede
+// the test point summarising it, skipped specs carry a SKIP directive, and
+// failures and hook exceptions are described in YAML diagnostic blocks.
+// Consumers limited to version 13 ignore the indented subtests and still see
+// one test point per top-level spec and child suite.
+func TAP(result suite.Result) string {
+	var builder strings.Builder
+	builder.WriteString("TAP version 14\n")
+	writeTAPSuite(&builder, result, "")
+	return builder.String()
+}
+
+func writeTAPSuite(builder *strings.Builder, result suite.Result, indent string) {
+	hooks := suiteHookExceptions(result)
+	fmt.Fprintf(builder, "%s1..%d\n", indent, len(result.SpecResults)+len(result.Children)+len(hooks))
+	number := 0
+	for _, hook := range hooks {
+		number += 1
+		fmt.Fprintf(builder, "%snot ok %d - %s %s\n", indent, number, hook.Kind, tapDescription(hook.Exception.Name))
+		writeTAPDiagnostics(builder, indent, []hookException{hook}, "")
+	}
+	for _, specResult := range result.SpecResults {
+		number += 1
+		specHooks := specHookExceptions(specResult)
+		switch {
+		case specResult.Status == "SKIPPED" && len(specHooks) == 0:
+			fmt.Fprintf(builder, "%sok %d - %s # SKIP%s\n", indent, number, tapDescription(specResult.Name), tapReason(specResult.Message))
+		case specResult.Status == "FAILED" || len(specHooks) > 0:
+			fmt.Fprintf(builder, "%snot ok %d - %s\n", indent, number, tapDescription(specResult.Name))
+		default:
+			fmt.Fprintf(builder, "%sok %d - %s\n", indent, number, tapDescription(specResult.Name))
+		}
+		failure := ""
+		if specResult.Status == "FAILED" {
+			failure = failureText(specResult)
+		}
+		writeTAPDiagnostics(builder, indent, specHooks, failure)
+	}
+	for _, child := range result.Children {
+		number += 1
+		fmt.Fprintf(builder, "%s# Subtest: %s\n", indent+"    ", tapDescription(child.Name))
+		writeTAPSuite(builder, child, indent+"    ")
+		if !child.Succeeded() {
+			fmt.Fprintf(builder, "%snot ok %d - %s\n", indent, number, tapDescription(child.Name))
+		} else {
+			fmt.Fprintf(builder, "%sok %d - %s\n", indent, number, tapDescription(child.Name))
+		}
+	}
+}
+
+// writeTAPDiagnostics writes a YAML block with the failure message and the
+// hook exceptions of a test point, if there are any.
+func writeTAPDiagnostics(builder *strings.Builder, indent string, hooks []hookException, failure string) {
+	if failure == "" && len(hooks) == 0 {
+		return
+	}
+	fmt.Fprintf(builder, "%s  ---\n", indent)
+	if failure != "" {
+		fmt.Fprintf(builder, "%s  message: |\n", indent)
+		writeTAPBlock(builder, indent+"    ", failure)
+	}
+	if len(hooks) > 0 {
+		fmt.Fprintf(builder, "%s  hook_exceptions:\n", indent)
+		for _, hook := range hooks {
+			fmt.Fprintf(builder, "%s    - hook: %s\n", indent, hook.Kind)
+			fmt.Fprintf(builder, "%s      name: %s\n", indent, yamlString(hook.Exception.Name))
+			fmt.Fprintf(builder, "%s      message: %s\n", indent, yamlString(hook.Exception.Message))
+			if hook.Exception.Location.File != "" {
+				fmt.Fprintf(builder, "%s      location: %s\n", indent, yamlString(hook.Exception.Location.String()))
+			}
+		}
+	}
+	fmt.Fprintf(builder, "%s  ...\n", indent)
+}
+func writeTAPBlock(builder *strings.Builder, indent string, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(builder, "%s%s\n", indent, line)
+	}
+}
+
+// tapDescription keeps a description on one line and escapes the characters
+// TAP gives a meaning to.
+func tapDescription(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	description = strings.Replace(description, "\\", "\\\\", -1)
+	return strings.Replace(description, "#", "\\#", -1)
+}
+func tapReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " " + tapDescription(reason)
+}
+func yamlString(value string) string {
+	return fmt.Sprintf("%q", value)
+}
@@ -0,0 +1,37 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTAPNestsSubtestsAndDescribesFailures(t *testing.T) {
+	tap := TAP(exampleResult())
+	lines := strings.Split(tap, "\n")
+
+	if lines[0] != "TAP version 14" || lines[1] != "1..5" {
+		t.Fatalf("expected version and plan but got %v", lines[:2])
+	}
+	expected := []string{
+		"not ok 1 - AfterAll should fail",
+		"ok 2 - should pass",
+		"not ok 3 - should fail",
+		"ok 4 - should skip # SKIP",
+		"    # Subtest: first child suite",
+		"    1..2",
+		"    not ok 1 - BeforeAll should fail",
+		"    ok 2 - should be skipped # SKIP",
+		"not ok 5 - first child suite",
+	}
+	for _, line := range expected {
+		if !strings.Contains(tap, line+"\n") {
+			t.Errorf("expected line '%s' in\n%s", line, tap)
+		}
+	}
+	if !strings.Contains(tap, "  message: |\n    expected 200 but got 500\n    at ") {
+		t.Errorf("expected yaml diagnostics with failure message in\n%s", tap)
+	}
+	if !strings.Contains(tap, "      message: \"setup failed\"\n") {
+		t.Errorf("expected yaml diagnostics with hook exception in\n%s", tap)
+	}
+}
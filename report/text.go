@@ -0,0 +1,146 @@
+package report
+
+import (
+	"fmt"
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"strings"
+)
+
+var textMarkers = map[string]string{
+	"PASSED":  "✓",
+	"FAILED":  "✗",
+	"SKIPPED": "-",
+}
+var dotMarkers = map[string]string{
+	"PASSED":  ".",
+	"FAILED":  "F",
+	"SKIPPED": "*",
+}
+
+// failureEntry is a failed spec or hook exception listed at the end of a text
+// report.
+type failureEntry struct {
+	Title string
+	Text  string
+}
+
+// Text renders result as an indented tree of suites and specs marked with ✓,
+// ✗ or -, followed by the details of every failure and a summary line. The
+// output only depends on the result, so specs of concurrent suites are
+// listed in declaration order.
+func Text(result suite.Result) string {
+	var builder strings.Builder
+	writeTextSuite(&builder, result, "")
+	writeTextFooter(&builder, result)
+	return builder.String()
+}
+
+// Dots renders result in the compact style of Jasmine's console reporter:
+// one character per spec ('.' passed, 'F' failed, '*' skipped) and 'E' per
+// hook exception, followed by the details of every failure and a summary
+// line.
+func Dots(result suite.Result) string {
+	var builder strings.Builder
+	writeDots(&builder, result)
+	builder.WriteString("\n")
+	writeTextFooter(&builder, result)
+	return builder.String()
+}
+
+func writeTextSuite(builder *strings.Builder, result suite.Result, indent string) {
+	fmt.Fprintf(builder, "%s%s\n", indent, result.Name)
+	for _, hook := range suiteHookExceptions(result) {
+		fmt.Fprintf(builder, "%s  ! %s '%s': %s\n", indent, hook.Kind, hook.Exception.Name, hook.Exception.Message)
+	}
+	for _, specResult := range result.SpecResults {
+		line := fmt.Sprintf("%s  %s %s", indent, textMarkers[specResult.Status], specResult.Name)
+		if specResult.Status == "SKIPPED" && specResult.Message != "" {
+			line += fmt.Sprintf(" (%s)", specResult.Message)
+		}
+		builder.WriteString(line + "\n")
+		for _, hook := range specHookExceptions(specResult) {
+			fmt.Fprintf(builder, "%s    ! %s '%s': %s\n", indent, hook.Kind, hook.Exception.Name, hook.Exception.Message)
+		}
+	}
+	for _, child := range result.Children {
+		writeTextSuite(builder, child, indent+"  ")
+	}
+}
+func writeDots(builder *strings.Builder, result suite.Result) {
+	for range suiteHookExceptions(result) {
+		builder.WriteString("E")
+	}
+	for _, specResult := range result.SpecResults {
+		builder.WriteString(dotMarkers[specResult.Status])
+		for range specHookExceptions(specResult) {
+			builder.WriteString("E")
+		}
+	}
+	for _, child := range result.Children {
+		writeDots(builder, child)
+	}
+}
+
+// writeTextFooter lists the failures of result and summarises its totals.
+func writeTextFooter(builder *strings.Builder, result suite.Result) {
+	failures := collectFailures(result, make([]failureEntry, 0))
+	if len(failures) > 0 {
+		builder.WriteString("\nFailures:\n")
+		for i, failure := range failures {
+			fmt.Fprintf(builder, "\n%d) %s\n", i+1, failure.Title)
+			for _, line := range strings.Split(failure.Text, "\n") {
+				fmt.Fprintf(builder, "   %s\n", line)
+			}
+		}
+	}
+	total := result.TotalPassed + result.TotalFailed + result.TotalSkipped
+	summary := fmt.Sprintf("\n%d %s, %d failed, %d skipped", total, plural(total, "spec"), result.TotalFailed, result.TotalSkipped)
+	if exceptions := countHookExceptions(result); exceptions > 0 {
+		summary += fmt.Sprintf(", %d hook %s", exceptions, plural(exceptions, "exception"))
+	}
+	builder.WriteString(summary + "\n")
+}
+func collectFailures(result suite.Result, failures []failureEntry) []failureEntry {
+	path := resultPath(result)
+	for _, hook := range suiteHookExceptions(result) {
+		failures = append(failures, failureEntry{Title: path, Text: exceptionText(hook)})
+	}
+	for _, specResult := range result.SpecResults {
+		specPath := specResult.Path
+		if specPath == "" {
+			specPath = path + suite.PathSeparator + specResult.Name
+		}
+		if specResult.Status == "FAILED" {
+			failures = append(failures, failureEntry{Title: specPath, Text: failureText(specResult)})
+		}
+		for _, hook := range specHookExceptions(specResult) {
+			failures = append(failures, failureEntry{Title: specPath, Text: exceptionText(hook)})
+		}
+	}
+	for _, child := range result.Children {
+		failures = collectFailures(child, failures)
+	}
+	return failures
+}
+func countHookExceptions(result suite.Result) int {
+	count := len(suiteHookExceptions(result))
+	for _, specResult := range result.SpecResults {
+		count += len(specHookExceptions(specResult))
+	}
+	for _, child := range result.Children {
+		count += countHookExceptions(child)
+	}
+	return count
+}
+func resultPath(result suite.Result) string {
+	if result.Path != "" {
+		return result.Path
+	}
+	return result.Name
+}
+func plural(count int, word string) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"github.com/hyperstripe50/gopher-jasmine/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextRendersTreeFailuresAndSummary(t *testing.T) {
+	text := Text(exampleResult())
+
+	expected := []string{
+		"parent suite",
+		"  ! AfterAll 'should fail': teardown failed",
+		"  ✓ should pass",
+		"  ✗ should fail",
+		"  - should skip",
+		"  first child suite",
+		"    ! BeforeAll 'should fail': setup failed",
+		"    - should be skipped",
+		"1) parent suite",
+		"   AfterAll 'should fail': teardown failed",
+		"2) parent suite > should fail",
+		"   expected 200 but got 500",
+		"3) parent suite > first child suite",
+		"   BeforeAll 'should fail': setup failed",
+		"4 specs, 1 failed, 2 skipped, 2 hook exceptions",
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("expected line '%s' in\n%s", line, text)
+		}
+	}
+}
+
+func TestDotsRendersOneCharacterPerSpec(t *testing.T) {
+	dots := Dots(exampleResult())
+
+	if !strings.HasPrefix(dots, "E.F*E*\n") {
+		t.Errorf("expected dots 'E.F*E*' but got\n%s", dots)
+	}
+	if !strings.HasSuffix(dots, "\n4 specs, 1 failed, 2 skipped, 2 hook exceptions\n") {
+		t.Errorf("expected summary line but got\n%s", dots)
+	}
+}
+
+func TestTextIsOrderedForConcurrentSuites(t *testing.T) {
+	var s suite.Suite = suite.NewConcurrentSuite("concurrent suite")
+	for _, name := range []string{"first", "second", "third"} {
+		delay := map[string]time.Duration{"first": 30, "second": 20, "third": 10}[name]
+		s = s.It(name, func(instance map[string]interface{}) error {
+			time.Sleep(delay * time.Millisecond)
+			return nil
+		})
+	}
+
+	text := Text(s.Run())
+
+	if !strings.HasPrefix(text, "concurrent suite\n  ✓ first\n  ✓ second\n  ✓ third\n") {
+		t.Errorf("expected specs in declaration order but got\n%s", text)
+	}
+}
@@ -0,0 +1,81 @@
+package suite
+
+import (
+	"fmt"
+	"sync"
+)
+
+// cleanupKey is the instance key under which a running suite keeps the
+// cleanups registered through DeferCleanup.
+const cleanupKey = "gopher-jasmine/cleanup"
+
+// DeferCleanup registers a cleanup from inside a hook or spec. Cleanups run in
+// reverse order of registration when the scope they were registered in ends,
+// even if the registering hook or spec failed:
+//   - from BeforeAll or AfterAll they run after AfterAll, or right after a
+//     failed BeforeAll;
+//   - from BeforeEach, It or AfterEach they run after AfterEach of that spec.
+//
+// Specs of a ConcurrentSuite share one instance, so there is no spec scope:
+// cleanups registered from BeforeEach, It or AfterEach of a concurrent spec
+// accumulate over all specs of the suite and only run after AfterAll.
+//
+// It returns an error and registers nothing if instance is not the instance of
+// a running suite, e.g. a map created by helper code.
+func DeferCleanup(instance map[string]interface{}, description string, cleanup func(instance map[string]interface{}) error) error {
+	registry, ok := instance[cleanupKey].(*cleanupRegistry)
+	if !ok {
+		return fmt.Errorf("DeferCleanup '%s' called outside of a running suite", description)
+	}
+	registry.add(Action{Description: description, Do: cleanup, Location: callerLocation()})
+	return nil
+}
+
+type cleanupRegistry struct {
+	mutex  sync.Mutex
+	scopes [][]Action
+}
+
+func installCleanupRegistry(instance map[string]interface{}) *cleanupRegistry {
+	registry := &cleanupRegistry{}
+	registry.push()
+	instance[cleanupKey] = registry
+	return registry
+}
+func (registry *cleanupRegistry) push() {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.scopes = append(registry.scopes, make([]Action, 0))
+}
+func (registry *cleanupRegistry) add(action Action) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	last := len(registry.scopes) - 1
+	registry.scopes[last] = append(registry.scopes[last], action)
+}
+
+// pop removes the innermost scope and runs its cleanups in reverse order,
+// returning an exception for each cleanup that failed.
+func (registry *cleanupRegistry) pop(instance map[string]interface{}) []ActionException {
+	registry.mutex.Lock()
+	last := len(registry.scopes) - 1
+	actions := registry.scopes[last]
+	registry.scopes = registry.scopes[:last]
+	registry.mutex.Unlock()
+	var exceptions []ActionException
+	for i := len(actions) - 1; i >= 0; i-- {
+		fmt.Printf("RUN Cleanup: %s\n", actions[i].Description)
+		if err := actions[i].Do(instance); err != nil {
+			exceptions = append(exceptions, *newActionException(&actions[i], err))
+		}
+	}
+	return exceptions
+}
+
+// close runs the cleanups of the suite scope and detaches the registry from the
+// instance once the suite finished.
+func (registry *cleanupRegistry) close(instance map[string]interface{}) []ActionException {
+	exceptions := registry.pop(instance)
+	delete(instance, cleanupKey)
+	return exceptions
+}
@@ -3,151 +3,189 @@ package suite
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 type ConcurrentSuite struct {
-	name        string
-	specs       []Spec
-	children    []Describe
-	beforeEach  *Action
-	beforeAll   *Action
-	afterEach   *Action
-	afterAll    *Action
-	instance    map[string]interface{}
-	result      Result
-	processStep func(action *Action) error
-	assert      func(spec *Spec) SpecResult
+	name       string
+	specs      []Spec
+	children   []Describe
+	beforeEach *Action
+	beforeAll  *Action
+	afterEach  *Action
+	afterAll   *Action
+	hooks      []Hook
+	tags       []string
+	parameters []Parameter
+	instance   map[string]interface{}
 }
 
 func NewConcurrentSuite(name string) *ConcurrentSuite {
-	return &ConcurrentSuite{name: name, instance: make(map[string]interface{}), result: Result{Name: name}}
+	return &ConcurrentSuite{name: name, instance: make(map[string]interface{})}
 }
 func (suite *ConcurrentSuite) GetName() string {
 	return suite.name
 }
+func (suite *ConcurrentSuite) GetSpecs() []Spec {
+	return suite.specs
+}
+func (suite *ConcurrentSuite) GetChildren() []Describe {
+	return suite.children
+}
+func (suite *ConcurrentSuite) GetHooks() []Hook {
+	return suite.hooks
+}
+func (suite *ConcurrentSuite) GetTags() []string {
+	return suite.tags
+}
+func (suite *ConcurrentSuite) GetParameters() []Parameter {
+	return suite.parameters
+}
+func (suite *ConcurrentSuite) Parameter(name string, kind string, defaultValue interface{}) *ConcurrentSuite {
+	suite.parameters = append(suite.parameters, Parameter{Name: name, Kind: kind, Default: defaultValue, Location: callerLocation()})
+	return suite
+}
+func (suite *ConcurrentSuite) Tag(tags ...string) *ConcurrentSuite {
+	suite.tags = append(suite.tags, tags...)
+	return suite
+}
 func (suite *ConcurrentSuite) Skip() Result {
-	suite.result.SpecResults = skipSpecsConcurrently(suite.specs)
-	suite.result.Children = skipChildrenConcurrently(suite.children)
-	return suite.result.CalculateResults()
+	return suite.RunWithOptions(RunOptions{}.skipping(""))
+}
+func (suite *ConcurrentSuite) skip(result Result, options RunOptions, reason string) Result {
+	result.SpecResults = skipSpecsConcurrently(suite.specs, options, reason)
+	result.Children = runChildrenConcurrently(suite.children, options.skipping(reason))
+	return result.CalculateResults()
 }
 func (suite *ConcurrentSuite) Run() Result {
+	return suite.RunWithOptions(RunOptions{})
+}
+func (suite *ConcurrentSuite) RunWithOptions(options RunOptions) Result {
+	options = options.enter(suite)
+	start := time.Now()
+	result := suite.run(options)
+	result.StartedAt = start
+	result.Duration = time.Since(start)
+	options.emitSuiteFinished(result)
+	return result
+}
+func (suite *ConcurrentSuite) run(options RunOptions) Result {
+	result := options.newResult()
+	if skip, reason := options.skipReason(suite); skip {
+		fmt.Printf("SKIP Concurrent Suite: %s\n", suite.name)
+		return suite.skip(result, options, reason)
+	}
 	fmt.Printf("RUN Concurrent Suite: %s\n", suite.name)
-	suite.processStep = createProcessStepFn(suite.instance)
-	suite.assert = createAssertFn(suite.instance)
-	err := suite.processStep(suite.beforeAll)
+	options.emitSuiteStarted()
+	injectParameters(suite.instance, options.Parameters)
+	processStep := createProcessStepFn(suite.instance)
+	assert := createAssertFn(suite.instance)
+	cleanups := installCleanupRegistry(suite.instance)
+	err := processStep(suite.beforeAll)
 	if err == nil {
-		suite.result.SpecResults = runSpecsConcurrently(suite.specs, suite.instance, suite.beforeEach, suite.assert, suite.afterEach)
-		suite.result.Children = runChildrenConcurrently(suite.children)
-		err = suite.processStep(suite.afterAll)
+		result.SpecResults = runSpecsConcurrently(suite.specs, suite.instance, suite.beforeEach, assert, suite.afterEach, options)
+		result.Children = runChildrenConcurrently(suite.children, options)
+		err = processStep(suite.afterAll)
 		if err != nil {
-			suite.result.AfterAllException = &ActionException{
-				Name:    suite.afterAll.Description,
-				Message: err.Error(),
-			}
+			result.AfterAllException = newActionException(suite.afterAll, err)
 		}
 	} else {
-		suite.result.BeforeAllException = &ActionException{
-			Name:    suite.beforeAll.Description,
-			Message: err.Error(),
-		}
-		return suite.Skip()
+		result.BeforeAllException = newActionException(suite.beforeAll, err)
+		result.CleanupExceptions = cleanups.close(suite.instance)
+		return suite.skip(result, options, "")
 	}
-	result := suite.result.CalculateResults()
+	result.CleanupExceptions = cleanups.close(suite.instance)
+	result = result.CalculateResults()
 	fmt.Printf("RESULTS for Suite '%s': passed: %d, skipped: %d, failed: %d\n", suite.GetName(), result.TotalPassed, result.TotalSkipped, result.TotalFailed)
 	return result
 }
 func (suite *ConcurrentSuite) BeforeEach(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.beforeEach = &Action{Description: description, Do: action}
+	suite.beforeEach = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: BeforeEachHook, Action: *suite.beforeEach})
 	return suite
 }
 func (suite *ConcurrentSuite) BeforeAll(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.beforeAll = &Action{Description: description, Do: action}
+	suite.beforeAll = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: BeforeAllHook, Action: *suite.beforeAll})
 	return suite
 }
 func (suite *ConcurrentSuite) AfterEach(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.afterEach = &Action{Description: description, Do: action}
+	suite.afterEach = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: AfterEachHook, Action: *suite.afterEach})
 	return suite
 }
 func (suite *ConcurrentSuite) AfterAll(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.afterAll = &Action{Description: description, Do: action}
+	suite.afterAll = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: AfterAllHook, Action: *suite.afterAll})
 	return suite
 }
 func (suite *ConcurrentSuite) It(description string, assertion func(instance map[string]interface{}) error) Suite {
-	suite.specs = append(suite.specs, Spec{Description: description, It: It{Do: assertion}})
+	suite.specs = append(suite.specs, Spec{Description: description, It: It{Do: assertion}, Location: callerLocation()})
 	return suite
 }
 func (suite *ConcurrentSuite) XIt(description string, assertion func(instance map[string]interface{}) error) Suite {
-	suite.specs = append(suite.specs, Spec{Description: description, Skip: true, It: It{Do: assertion}})
+	suite.specs = append(suite.specs, Spec{Description: description, Skip: true, It: It{Do: assertion}, Location: callerLocation()})
+	return suite
+}
+
+// FIt declares a spec marked as focused in the Plan. It runs like one declared
+// with It.
+func (suite *ConcurrentSuite) FIt(description string, assertion func(instance map[string]interface{}) error) *ConcurrentSuite {
+	suite.specs = append(suite.specs, Spec{Description: description, Focus: true, It: It{Do: assertion}, Location: callerLocation()})
 	return suite
 }
 func (suite *ConcurrentSuite) Describe(children Suite) Suite {
-	suite.children = append(suite.children, Describe{Suite: children})
+	suite.children = append(suite.children, Describe{Suite: children, Location: callerLocation()})
 	return suite
 }
 func (suite *ConcurrentSuite) XDescribe(children Suite) Suite {
-	suite.children = append(suite.children, Describe{Skip: true, Suite: children})
+	suite.children = append(suite.children, Describe{Skip: true, Suite: children, Location: callerLocation()})
+	return suite
+}
+
+// FDescribe declares a child suite marked as focused in the Plan. It runs like
+// one declared with Describe.
+func (suite *ConcurrentSuite) FDescribe(children Suite) *ConcurrentSuite {
+	suite.children = append(suite.children, Describe{Focus: true, Suite: children, Location: callerLocation()})
 	return suite
 }
 
-func runSpecsConcurrently(specs []Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action) []SpecResult {
+func runSpecsConcurrently(specs []Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action, options RunOptions) []SpecResult {
 	results := make([]SpecResult, len(specs))
 	var wg sync.WaitGroup
 	for index, spec := range specs {
 		wg.Add(1)
 		go func(s Spec, i int) {
 			defer wg.Done()
-			if !s.Skip {
-				results[i] = runSpec(s, instance, beforeEach, assert, afterEach)
-			} else {
-				fmt.Printf("SKIP Spec: %s\n", s.Description)
-				results = append(results, SpecResult{
-					Name:                s.Description,
-					Status:              "SKIPPED",
-					BeforeEachException: nil,
-					AfterEachException:  nil,
-				})
-			}
+			results[i] = runSpec(s, instance, beforeEach, assert, afterEach, nil, options)
 		}(spec, index)
 	}
 	wg.Wait()
 	return results
 }
-func runChildrenConcurrently(children []Describe) []Result {
+func runChildrenConcurrently(children []Describe, options RunOptions) []Result {
 	results := make([]Result, len(children))
 	var wg sync.WaitGroup
 	for index, child := range children {
 		wg.Add(1)
 		go func(c Describe, i int) {
 			defer wg.Done()
-			results[i] = runChild(c)
+			results[i] = runChild(c, options)
 		}(child, index)
 	}
 	wg.Wait()
 	return results
 }
-func skipSpecsConcurrently(specs []Spec) []SpecResult {
+func skipSpecsConcurrently(specs []Spec, options RunOptions, reason string) []SpecResult {
 	results := make([]SpecResult, len(specs))
 	var wg sync.WaitGroup
 	for index, spec := range specs {
 		wg.Add(1)
 		go func(s Spec, i int) {
 			defer wg.Done()
-			results[i] = skipSpec(s)
+			results[i] = skipSpec(s, options, reason)
 		}(spec, index)
 	}
 	wg.Wait()
 	return results
 }
-func skipChildrenConcurrently(children []Describe) []Result {
-	results := make([]Result, len(children))
-	var wg sync.WaitGroup
-	for index, child := range children {
-		wg.Add(1)
-		go func(c Describe, i int) {
-			defer wg.Done()
-			results[i] = skipChild(c)
-		}(child, index)
-	}
-	wg.Wait()
-	return results
-}
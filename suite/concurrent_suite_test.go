@@ -2,6 +2,7 @@ package suite
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -212,3 +213,77 @@ func TestConcurrentSuiteWithAfterAll(t *testing.T) {
 			return nil
 		}).Run()
 }
+func TestConcurrentSuiteFailFastSkipsChildren(t *testing.T) {
+	childBeforeAllRan := false
+	result := RunWithOptions(NewConcurrentSuite("parent suite").
+		It("should fail", func(instance map[string]interface{}) error {
+			return fmt.Errorf("exit 1")
+		}).
+		Describe(NewConcurrentSuite("first child suite").
+			BeforeAll("should not run after abort", func(instance map[string]interface{}) error {
+				childBeforeAllRan = true
+				return nil
+			}).
+			It("should not run after abort", func(instance map[string]interface{}) error {
+				return nil
+			})), RunOptions{FailFast: true})
+
+	if childBeforeAllRan {
+		t.Errorf("expected child before all not to run after abort")
+	}
+	if result.TotalFailed != 1 {
+		t.Errorf("expected 1 total failed but got %d", result.TotalFailed)
+	}
+	if result.TotalSkipped != 1 {
+		t.Errorf("expected 1 total skipped but got %d", result.TotalSkipped)
+	}
+}
+func TestConcurrentSuiteRunsCleanupsOfParallelSpecsAfterAfterAll(t *testing.T) {
+	var mutex sync.Mutex
+	ran := make([]string, 0)
+	record := func(name string, err error) func(instance map[string]interface{}) error {
+		return func(instance map[string]interface{}) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			ran = append(ran, name)
+			return err
+		}
+	}
+	firstRegistered := make(chan bool)
+	secondRegistered := make(chan bool)
+	result := NewConcurrentSuite("parent suite").
+		It("registers first and last", func(instance map[string]interface{}) error {
+			DeferCleanup(instance, "first", record("first", nil))
+			close(firstRegistered)
+			<-secondRegistered
+			DeferCleanup(instance, "last", record("last", nil))
+			return fmt.Errorf("exit 1")
+		}).
+		It("registers second", func(instance map[string]interface{}) error {
+			<-firstRegistered
+			DeferCleanup(instance, "second", record("second", fmt.Errorf("still in use")))
+			close(secondRegistered)
+			return nil
+		}).
+		AfterAll("after all", record("after all", nil)).
+		Run()
+
+	expected := []string{"after all", "last", "second", "first"}
+	if fmt.Sprint(ran) != fmt.Sprint(expected) {
+		t.Errorf("expected %v but got %v", expected, ran)
+	}
+	if len(result.CleanupExceptions) != 1 || result.CleanupExceptions[0].Name != "second" || result.CleanupExceptions[0].Message != "still in use" {
+		t.Errorf("expected the failed cleanup to be reported with the suite but got %v", result.CleanupExceptions)
+	}
+	for _, specResult := range result.SpecResults {
+		if len(specResult.CleanupExceptions) != 0 {
+			t.Errorf("expected no cleanup exceptions on spec '%s' but got %v", specResult.Name, specResult.CleanupExceptions)
+		}
+	}
+	if result.TotalFailed != 1 || result.Succeeded() {
+		t.Errorf("expected the failed spec and cleanup to fail the suite but got %d failed", result.TotalFailed)
+	}
+	if err := DeferCleanup(make(map[string]interface{}), "outside", record("outside", nil)); err == nil {
+		t.Errorf("expected DeferCleanup outside of a running suite to fail")
+	}
+}
@@ -0,0 +1,77 @@
+package suite
+
+import (
+	"time"
+)
+
+const (
+	SuiteStartedEvent  = "suite_started"
+	SuiteFinishedEvent = "suite_finished"
+	SpecPassedEvent    = "spec_passed"
+	SpecFailedEvent    = "spec_failed"
+	SpecSkippedEvent   = "spec_skipped"
+	HookFailedEvent    = "hook_failed"
+	RunFinishedEvent   = "run_finished"
+)
+
+// Event reports the progress of a run to the Listener of its RunOptions.
+type Event struct {
+	Type       string           `json:"type"`
+	Time       time.Time        `json:"time"`
+	Path       string           `json:"path"`
+	ID         string           `json:"id"`
+	SpecResult *SpecResult      `json:"spec_result,omitempty"`
+	Result     *Result          `json:"result,omitempty"`
+	Hook       string           `json:"hook,omitempty"`
+	Exception  *ActionException `json:"exception,omitempty"`
+}
+
+// Listener receives the events of a run. Specs and suites of a
+// ConcurrentSuite report from several goroutines at once, so a Listener must
+// be safe for concurrent use.
+type Listener func(event Event)
+
+func (options RunOptions) emit(event Event) {
+	if options.Listener == nil {
+		return
+	}
+	event.Time = time.Now()
+	options.Listener(event)
+}
+func (options RunOptions) emitSuiteStarted() {
+	options.emit(Event{Type: SuiteStartedEvent, Path: JoinPath(options.path), ID: PathID(options.path)})
+}
+
+// emitSuiteFinished reports the hook exceptions of the suite itself, then the
+// suite result, and the totals of the run once its root suite finished.
+func (options RunOptions) emitSuiteFinished(result Result) {
+	options.emitHookFailed(result.Path, result.ID, BeforeAllHook, result.BeforeAllException)
+	options.emitHookFailed(result.Path, result.ID, AfterAllHook, result.AfterAllException)
+	for i := range result.CleanupExceptions {
+		options.emitHookFailed(result.Path, result.ID, CleanupHook, &result.CleanupExceptions[i])
+	}
+	options.emit(Event{Type: SuiteFinishedEvent, Path: result.Path, ID: result.ID, Result: &result})
+	if len(options.path) == 1 {
+		options.emit(Event{Type: RunFinishedEvent, Path: result.Path, ID: result.ID, Result: &result})
+	}
+}
+func (options RunOptions) emitHookFailed(path string, id string, hook string, exception *ActionException) {
+	if exception != nil {
+		options.emit(Event{Type: HookFailedEvent, Path: path, ID: id, Hook: hook, Exception: exception})
+	}
+}
+func (options RunOptions) emitSpec(specResult SpecResult) {
+	eventType := SpecPassedEvent
+	switch specResult.Status {
+	case "FAILED":
+		eventType = SpecFailedEvent
+	case "SKIPPED":
+		eventType = SpecSkippedEvent
+	}
+	options.emitHookFailed(specResult.Path, specResult.ID, BeforeEachHook, specResult.BeforeEachException)
+	options.emitHookFailed(specResult.Path, specResult.ID, AfterEachHook, specResult.AfterEachException)
+	for i := range specResult.CleanupExceptions {
+		options.emitHookFailed(specResult.Path, specResult.ID, CleanupHook, &specResult.CleanupExceptions[i])
+	}
+	options.emit(Event{Type: eventType, Path: specResult.Path, ID: specResult.ID, SpecResult: &specResult})
+}
@@ -0,0 +1,58 @@
+package suite
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Location is a position in the source code of the suites.
+type Location struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (location Location) String() string {
+	return fmt.Sprintf("%s:%d", location.File, location.Line)
+}
+
+// callerLocation returns the location of the code that called the function
+// calling callerLocation.
+func callerLocation() Location {
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return Location{}
+	}
+	return Location{File: file, Line: line}
+}
+
+// LocatedError is an error that remembers where it was created, so that a
+// failing spec or hook can report where the failure originated.
+type LocatedError struct {
+	Err      error
+	Location Location
+}
+
+func (err *LocatedError) Error() string {
+	return err.Err.Error()
+}
+func (err *LocatedError) Unwrap() error {
+	return err.Err
+}
+
+// Errorf formats an error like fmt.Errorf and records the location it was
+// called from. Returning it from a spec or hook reports that location as the
+// failure location of the result.
+func Errorf(format string, args ...interface{}) error {
+	return &LocatedError{Err: fmt.Errorf(format, args...), Location: callerLocation()}
+}
+
+// failureLocation returns the location err was created at, if it is known.
+func failureLocation(err error) *Location {
+	var located *LocatedError
+	if errors.As(err, &located) {
+		location := located.Location
+		return &location
+	}
+	return nil
+}
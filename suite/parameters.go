@@ -0,0 +1,182 @@
+package suite
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	StringParameter = "string"
+	IntParameter    = "int"
+	FloatParameter  = "float"
+	BoolParameter   = "bool"
+)
+
+var parameterKinds = map[string]bool{
+	StringParameter: true,
+	IntParameter:    true,
+	FloatParameter:  true,
+	BoolParameter:   true,
+}
+
+// Parameter is a named input of a suite tree that is provided at run time. A
+// Parameter without Default is required.
+type Parameter struct {
+	Name     string      `json:"name"`
+	Kind     string      `json:"kind"`
+	Default  interface{} `json:"default"`
+	Location Location    `json:"location"`
+}
+
+// ParameterError is returned by ResolveParameters when values are missing,
+// unknown or of the wrong kind.
+type ParameterError struct {
+	Name    string
+	Message string
+}
+
+func (err *ParameterError) Error() string {
+	return fmt.Sprintf("parameter '%s' %s", err.Name, err.Message)
+}
+
+// GetParameters returns the parameters declared anywhere in the suite tree,
+// parents first. When several suites declare the same name the first
+// declaration wins.
+func GetParameters(suite Suite) []Parameter {
+	parameters := make([]Parameter, 0)
+	declared := make(map[string]bool)
+	collectParameters(suite, &parameters, declared)
+	return parameters
+}
+func collectParameters(suite Suite, parameters *[]Parameter, declared map[string]bool) {
+	for _, parameter := range declaredParameters(suite) {
+		if !declared[parameter.Name] {
+			*parameters = append(*parameters, parameter)
+			declared[parameter.Name] = true
+		}
+	}
+	for _, child := range GetChildren(suite) {
+		collectParameters(child.Suite, parameters, declared)
+	}
+}
+
+// ResolveParameters checks values against the parameters declared in the
+// suite tree and returns the value of every parameter, falling back to the
+// defaults. Values may be strings, as found in query parameters, or values of
+// the parameter's kind, as decoded from JSON.
+func ResolveParameters(suite Suite, values map[string]interface{}) (map[string]interface{}, error) {
+	parameters := GetParameters(suite)
+	declared := make(map[string]bool)
+	for _, parameter := range parameters {
+		declared[parameter.Name] = true
+	}
+	for name := range values {
+		if !declared[name] {
+			return nil, &ParameterError{Name: name, Message: fmt.Sprintf("is not declared by suite '%s'", suite.GetName())}
+		}
+	}
+	resolved := make(map[string]interface{})
+	for _, parameter := range parameters {
+		value, ok := values[parameter.Name]
+		if !ok {
+			value = parameter.Default
+		}
+		if value == nil {
+			return nil, &ParameterError{Name: parameter.Name, Message: "is required"}
+		}
+		converted, err := convertParameter(parameter.Kind, value)
+		if err != nil {
+			return nil, &ParameterError{Name: parameter.Name, Message: err.Error()}
+		}
+		resolved[parameter.Name] = converted
+	}
+	return resolved, nil
+}
+
+// defaultParameters returns the defaults of the parameters declared in the
+// suite tree, used when a run is started without Parameters, and a problem
+// for every required parameter.
+func defaultParameters(suite Suite) (map[string]interface{}, []Problem) {
+	defaults := make(map[string]interface{})
+	problems := make([]Problem, 0)
+	for _, parameter := range GetParameters(suite) {
+		if parameter.Default == nil {
+			location := parameter.Location
+			problems = append(problems, Problem{
+				Kind:     MissingParameterProblem,
+				Path:     suite.GetName(),
+				Message:  fmt.Sprintf("parameter '%s' is required but the run of suite '%s' has no Parameters", parameter.Name, suite.GetName()),
+				Location: &location,
+			})
+			continue
+		}
+		if value, err := convertParameter(parameter.Kind, parameter.Default); err == nil {
+			defaults[parameter.Name] = value
+		}
+	}
+	return defaults, problems
+}
+
+// convertParameter turns value into a value of kind.
+func convertParameter(kind string, value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok && kind != StringParameter {
+		return parseParameter(kind, text)
+	}
+	switch kind {
+	case StringParameter:
+		if text, ok := value.(string); ok {
+			return text, nil
+		}
+	case IntParameter:
+		switch number := value.(type) {
+		case int:
+			return number, nil
+		case float64:
+			if number == math.Trunc(number) {
+				return int(number), nil
+			}
+		}
+	case FloatParameter:
+		switch number := value.(type) {
+		case int:
+			return float64(number), nil
+		case float64:
+			return number, nil
+		}
+	case BoolParameter:
+		if flag, ok := value.(bool); ok {
+			return flag, nil
+		}
+	default:
+		return nil, fmt.Errorf("has unknown kind '%s'", kind)
+	}
+	return nil, fmt.Errorf("must be of kind %s but got %v", kind, value)
+}
+func parseParameter(kind string, text string) (interface{}, error) {
+	switch kind {
+	case IntParameter:
+		if number, err := strconv.Atoi(text); err == nil {
+			return number, nil
+		}
+	case FloatParameter:
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number, nil
+		}
+	case BoolParameter:
+		if flag, err := strconv.ParseBool(text); err == nil {
+			return flag, nil
+		}
+	default:
+		return nil, fmt.Errorf("has unknown kind '%s'", kind)
+	}
+	return nil, fmt.Errorf("must be of kind %s but got '%s'", kind, text)
+}
+
+// injectParameters exposes the parameter values of the run to the hooks and
+// specs of a suite under the parameter names.
+func injectParameters(instance map[string]interface{}, parameters map[string]interface{}) {
+	for name, value := range parameters {
+		instance[name] = value
+	}
+}
@@ -0,0 +1,101 @@
+package suite
+
+import (
+	"testing"
+)
+
+func TestParametersAreInjectedBeforeBeforeAll(t *testing.T) {
+	seen := make(map[string]interface{})
+	s := NewSequentialSuite("parent suite").
+		Parameter("base_url", StringParameter, nil).
+		Parameter("retries", IntParameter, 3).
+		BeforeAll("records parameters", func(instance map[string]interface{}) error {
+			seen["base_url"] = instance["base_url"]
+			return nil
+		}).
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		Describe(NewConcurrentSuite("first child suite").
+			Parameter("verbose", BoolParameter, false).
+			It("sees parent parameters", func(instance map[string]interface{}) error {
+				seen["retries"] = instance["retries"]
+				seen["verbose"] = instance["verbose"]
+				return nil
+			}))
+
+	parameters, err := ResolveParameters(s, map[string]interface{}{"base_url": "http://localhost", "verbose": "true"})
+	if err != nil {
+		t.Fatalf("expected parameters to resolve but got %s", err.Error())
+	}
+	RunWithOptions(s, RunOptions{Parameters: parameters})
+
+	if seen["base_url"] != "http://localhost" || seen["retries"] != 3 || seen["verbose"] != true {
+		t.Errorf("expected parameters in every instance but got %v", seen)
+	}
+}
+
+func TestRunWithoutRequiredParametersReportsProblems(t *testing.T) {
+	ran := false
+	s := NewSequentialSuite("parent suite").
+		Parameter("base_url", StringParameter, nil).
+		Parameter("retries", IntParameter, 3).
+		It("returns 200", func(instance map[string]interface{}) error {
+			ran = true
+			return nil
+		})
+
+	result := s.Run()
+	if ran || result.TotalSkipped != 1 || result.Succeeded() {
+		t.Errorf("expected run to be skipped and fail but got ran %v, %d skipped", ran, result.TotalSkipped)
+	}
+	if len(result.Problems) != 1 || result.Problems[0].Kind != MissingParameterProblem {
+		t.Errorf("expected missing parameter problem but got %v", result.Problems)
+	}
+}
+
+func TestResolveParametersRejectsInvalidValues(t *testing.T) {
+	s := NewSequentialSuite("parent suite").
+		Parameter("base_url", StringParameter, nil).
+		Parameter("retries", IntParameter, 3).
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		})
+
+	if _, err := ResolveParameters(s, map[string]interface{}{}); err == nil || err.Error() != "parameter 'base_url' is required" {
+		t.Errorf("expected missing parameter error but got %v", err)
+	}
+	if _, err := ResolveParameters(s, map[string]interface{}{"base_url": "x", "retries": "many"}); err == nil {
+		t.Errorf("expected error for int parameter 'many'")
+	}
+	if _, err := ResolveParameters(s, map[string]interface{}{"base_url": "x", "tenant": "y"}); err == nil {
+		t.Errorf("expected error for undeclared parameter")
+	}
+	parameters, err := ResolveParameters(s, map[string]interface{}{"base_url": "x", "retries": float64(5)})
+	if err != nil || parameters["retries"] != 5 {
+		t.Errorf("expected json number to resolve to int 5 but got %v (%v)", parameters["retries"], err)
+	}
+}
+
+func TestValidateReportsInvalidParameters(t *testing.T) {
+	problems := Validate(NewSequentialSuite("parent suite").
+		Parameter("retries", IntParameter, "three").
+		Parameter("tenant", "uuid", nil).
+		It("returns 200", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		Describe(NewSequentialSuite("first child suite").
+			Parameter("retries", StringParameter, nil).
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			})))
+
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems but got %v", problems)
+	}
+	for _, problem := range problems {
+		if problem.Kind != InvalidParameterProblem {
+			t.Errorf("expected invalid parameter problem but got %v", problem)
+		}
+	}
+}
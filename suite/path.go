@@ -0,0 +1,67 @@
+package suite
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PathSeparator joins the names of the suites and the spec description that
+// lead to a spec, e.g. "parent suite > first child suite > returns 200".
+const PathSeparator = " > "
+
+// PathID returns the stable ID of the suite or spec reached by path. The ID
+// only depends on the names along the path, so it survives restarts and can
+// be used to select the same spec again.
+func PathID(path []string) string {
+	sum := sha256.Sum256([]byte(JoinPath(path)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// JoinPath joins the elements of path with PathSeparator.
+func JoinPath(path []string) string {
+	return strings.Join(path, PathSeparator)
+}
+
+func appendPath(path []string, name string) []string {
+	appended := make([]string, len(path), len(path)+1)
+	copy(appended, path)
+	return append(appended, name)
+}
+
+// selects reports whether path, or one of the suites leading to it, was
+// selected by ID or by joined path.
+func (options RunOptions) selects(path []string) bool {
+	if len(options.Select) == 0 {
+		return true
+	}
+	for i := 1; i <= len(path); i++ {
+		joined := JoinPath(path[:i])
+		id := PathID(path[:i])
+		for _, selection := range options.Select {
+			if selection == joined || selection == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enters reports whether the suite at path has to run, that is whether it is
+// selected itself or contains a selected spec or suite.
+func (options RunOptions) enters(suite Suite, path []string) bool {
+	if options.selects(path) {
+		return true
+	}
+	for _, spec := range GetSpecs(suite) {
+		if options.selects(appendPath(path, spec.Description)) {
+			return true
+		}
+	}
+	for _, child := range GetChildren(suite) {
+		if options.enters(child.Suite, appendPath(path, child.Suite.GetName())) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package suite
+
+// Plan describes a suite tree as it would run, without invoking any hook or
+// spec. It is the result of DryRun.
+type Plan struct {
+	Name       string      `json:"name"`
+	Path       string      `json:"path"`
+	ID         string      `json:"id"`
+	Skip       bool        `json:"skip"`
+	Focus      bool        `json:"focus"`
+	Tags       []string    `json:"tags"`
+	Parameters []Parameter `json:"parameters"`
+	Location   *Location   `json:"location"`
+	Hooks      []HookPlan  `json:"hooks"`
+	Specs      []SpecPlan  `json:"specs"`
+	Children   []Plan      `json:"children"`
+}
+type HookPlan struct {
+	Kind        string   `json:"kind"`
+	Description string   `json:"description"`
+	Location    Location `json:"location"`
+}
+type SpecPlan struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	ID       string   `json:"id"`
+	Skip     bool     `json:"skip"`
+	Focus    bool     `json:"focus"`
+	Location Location `json:"location"`
+}
+
+// DryRun walks the suite tree and returns its structure: suites, specs, the
+// hooks that would run, skip and focus flags, tags and declared parameters.
+func DryRun(suite Suite) Plan {
+	return planSuite(suite, []string{suite.GetName()}, Describe{Suite: suite}, nil)
+}
+
+func planSuite(suite Suite, path []string, describe Describe, location *Location) Plan {
+	tags := GetTags(suite)
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	parameters := declaredParameters(suite)
+	if parameters == nil {
+		parameters = make([]Parameter, 0)
+	}
+	plan := Plan{
+		Name:       suite.GetName(),
+		Path:       JoinPath(path),
+		ID:         PathID(path),
+		Skip:       describe.Skip,
+		Focus:      describe.Focus,
+		Tags:       tags,
+		Parameters: parameters,
+		Location:   location,
+		Hooks:      planHooks(GetHooks(suite)),
+		Specs:      make([]SpecPlan, 0),
+		Children:   make([]Plan, 0),
+	}
+	for _, spec := range GetSpecs(suite) {
+		specPath := appendPath(path, spec.Description)
+		plan.Specs = append(plan.Specs, SpecPlan{
+			Name:     spec.Description,
+			Path:     JoinPath(specPath),
+			ID:       PathID(specPath),
+			Skip:     spec.Skip,
+			Focus:    spec.Focus,
+			Location: spec.Location,
+		})
+	}
+	for _, child := range GetChildren(suite) {
+		childLocation := child.Location
+		plan.Children = append(plan.Children, planSuite(child.Suite, appendPath(path, child.Suite.GetName()), child, &childLocation))
+	}
+	return plan
+}
+
+// planHooks returns the hooks that take effect, i.e. the last registration of
+// each kind, in the order they run.
+func planHooks(hooks []Hook) []HookPlan {
+	effective := make(map[string]Hook)
+	for _, hook := range hooks {
+		effective[hook.Kind] = hook
+	}
+	plans := make([]HookPlan, 0)
+	for _, kind := range []string{BeforeAllHook, BeforeEachHook, AfterEachHook, AfterAllHook} {
+		if hook, ok := effective[kind]; ok {
+			plans = append(plans, HookPlan{
+				Kind:        hook.Kind,
+				Description: hook.Action.Description,
+				Location:    hook.Action.Location,
+			})
+		}
+	}
+	return plans
+}
@@ -0,0 +1,41 @@
+package suite
+
+import (
+	"testing"
+)
+
+func TestDryRunDescribesTreeWithoutRunningIt(t *testing.T) {
+	ran := false
+	do := func(instance map[string]interface{}) error {
+		ran = true
+		return nil
+	}
+	s := NewSequentialSuite("parent suite").Tag("smoke")
+	s.BeforeAll("before all", do).
+		It("top level test", do).
+		XIt("skipped test", do)
+	s.FDescribe(NewConcurrentSuite("first child suite").
+		AfterEach("after each", do).
+		It("returns 200", do))
+	plan := DryRun(s)
+
+	if ran {
+		t.Errorf("expected dry run not to invoke any callback")
+	}
+	if len(plan.Tags) != 1 || plan.Tags[0] != "smoke" {
+		t.Errorf("expected tags [smoke] but got %v", plan.Tags)
+	}
+	if len(plan.Hooks) != 1 || plan.Hooks[0].Kind != BeforeAllHook {
+		t.Errorf("expected one before all hook but got %v", plan.Hooks)
+	}
+	if len(plan.Specs) != 2 || !plan.Specs[1].Skip {
+		t.Errorf("expected second spec to be skipped but got %v", plan.Specs)
+	}
+	child := plan.Children[0]
+	if !child.Focus || child.Location == nil {
+		t.Errorf("expected focused child with location but got %v", child)
+	}
+	if child.Specs[0].ID != PathID([]string{"parent suite", "first child suite", "returns 200"}) {
+		t.Errorf("expected spec id to match the id of its result but got %s", child.Specs[0].ID)
+	}
+}
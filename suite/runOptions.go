@@ -0,0 +1,147 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RunOptions controls how a suite tree is executed by RunWithOptions. The zero
+// value runs every spec, which is what Run does.
+type RunOptions struct {
+	// FailFast stops the run after the first failed spec anywhere in the tree.
+	FailFast bool
+	// MaxFailures stops the run once this many specs failed anywhere in the
+	// tree. Zero means there is no failure budget.
+	//
+	// Both only stop specs that did not start yet. The specs and child suites
+	// of a ConcurrentSuite start at once, so they all run to the end and can
+	// overshoot the budget.
+	MaxFailures int
+	// Select restricts the run to the specs and suites with these IDs or
+	// paths (see PathID and JoinPath). Other specs are reported as SKIPPED and
+	// suites without selected specs are skipped without running their hooks.
+	// An empty Select runs everything.
+	Select []string
+	// Context cancels the run: once it is done, specs and suites that did not
+	// start yet are reported as SKIPPED with the message "cancelled". Specs
+	// that are already running finish normally.
+	Context context.Context
+	// Listener, if set, is told about the progress of the run.
+	Listener Listener
+	// Parameters are the values of the parameters declared in the suite tree,
+	// as returned by ResolveParameters. They are put into the instance of
+	// every suite under the parameter names before its BeforeAll runs. When
+	// nil, the defaults of the declared parameters are used; if a parameter
+	// without default is declared, the run is skipped and its result reports
+	// the missing parameters as Problems.
+	Parameters map[string]interface{}
+
+	state       *runState
+	path        []string
+	skip        bool
+	skipMessage string
+}
+
+// runState is shared by every suite of one run so that failures in one child
+// suite are visible to its siblings and parents.
+type runState struct {
+	mutex    sync.Mutex
+	failures int
+	problems []Problem
+}
+
+func (options RunOptions) withState() RunOptions {
+	if options.state == nil {
+		options.state = &runState{}
+	}
+	return options
+}
+
+// enter returns the options for running suite below the suite the options
+// currently point at. Entering the root of a run resolves the default
+// parameters.
+func (options RunOptions) enter(suite Suite) RunOptions {
+	options = options.withState()
+	if len(options.path) == 0 {
+		if options.Parameters == nil && !options.skip {
+			var problems []Problem
+			options.Parameters, problems = defaultParameters(suite)
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					fmt.Printf("INVALID Suite: %s\n", problem)
+				}
+				options.state.problems = problems
+				options = options.skipping("missing required parameters")
+			}
+		}
+	}
+	options.path = appendPath(options.path, suite.GetName())
+	return options
+}
+
+// skipping returns options that skip every spec and suite they are passed to,
+// reporting message on the skipped specs.
+func (options RunOptions) skipping(message string) RunOptions {
+	options.skip = true
+	options.skipMessage = message
+	return options
+}
+
+// skipReason reports whether the suite the options point at must be skipped
+// and with which message.
+func (options RunOptions) skipReason(suite Suite) (bool, string) {
+	if options.skip {
+		return true, options.skipMessage
+	}
+	if reason := options.stopReason(); reason != "" {
+		return true, reason
+	}
+	if !options.enters(suite, options.path) {
+		return true, "not selected"
+	}
+	return false, ""
+}
+func (options RunOptions) newResult() Result {
+	result := Result{Name: options.path[len(options.path)-1], Path: JoinPath(options.path), ID: PathID(options.path)}
+	if len(options.path) == 1 {
+		result.Problems = options.state.problems
+	}
+	return result
+}
+
+func (options RunOptions) failureLimit() int {
+	if options.FailFast {
+		return 1
+	}
+	return options.MaxFailures
+}
+func (options RunOptions) recordResult(result SpecResult) {
+	if options.state == nil || result.Status != "FAILED" {
+		return
+	}
+	options.state.mutex.Lock()
+	defer options.state.mutex.Unlock()
+	options.state.failures += 1
+}
+
+// stopReason returns why the remaining specs of the run must not be executed,
+// or an empty string when the run should go on.
+func (options RunOptions) stopReason() string {
+	if options.Context != nil && options.Context.Err() != nil {
+		return "cancelled"
+	}
+	limit := options.failureLimit()
+	if options.state == nil || limit <= 0 {
+		return ""
+	}
+	options.state.mutex.Lock()
+	defer options.state.mutex.Unlock()
+	if options.state.failures >= limit {
+		if limit == 1 {
+			return "aborted after 1 failure"
+		}
+		return fmt.Sprintf("aborted after %d failures", limit)
+	}
+	return ""
+}
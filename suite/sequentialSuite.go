@@ -1,130 +1,175 @@
 package suite
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type SequentialSuite struct {
-	name        string
-	specs       []Spec
-	children    []Describe
-	beforeEach  *Action
-	beforeAll   *Action
-	afterEach   *Action
-	afterAll    *Action
-	instance    map[string]interface{}
-	result      Result
-	processStep func(action *Action) error
-	assert      func(spec *Spec) SpecResult
+	name       string
+	specs      []Spec
+	children   []Describe
+	beforeEach *Action
+	beforeAll  *Action
+	afterEach  *Action
+	afterAll   *Action
+	hooks      []Hook
+	tags       []string
+	parameters []Parameter
+	instance   map[string]interface{}
 }
 
 func NewSequentialSuite(name string) *SequentialSuite {
 	return &SequentialSuite{
 		name:     name,
 		instance: make(map[string]interface{}),
-		result:   Result{Name: name},
 	}
 }
 func (suite *SequentialSuite) GetName() string {
 	return suite.name
 }
+func (suite *SequentialSuite) GetSpecs() []Spec {
+	return suite.specs
+}
+func (suite *SequentialSuite) GetChildren() []Describe {
+	return suite.children
+}
+func (suite *SequentialSuite) GetHooks() []Hook {
+	return suite.hooks
+}
+func (suite *SequentialSuite) GetTags() []string {
+	return suite.tags
+}
+func (suite *SequentialSuite) GetParameters() []Parameter {
+	return suite.parameters
+}
+func (suite *SequentialSuite) Parameter(name string, kind string, defaultValue interface{}) *SequentialSuite {
+	suite.parameters = append(suite.parameters, Parameter{Name: name, Kind: kind, Default: defaultValue, Location: callerLocation()})
+	return suite
+}
+func (suite *SequentialSuite) Tag(tags ...string) *SequentialSuite {
+	suite.tags = append(suite.tags, tags...)
+	return suite
+}
 func (suite *SequentialSuite) Skip() Result {
-	suite.result.SpecResults = skipSpecsSequentially(suite.specs)
-	suite.result.Children = skipChildrenSequentially(suite.children)
-	return suite.result.CalculateResults()
+	return suite.RunWithOptions(RunOptions{}.skipping(""))
+}
+func (suite *SequentialSuite) skip(result Result, options RunOptions, reason string) Result {
+	result.SpecResults = skipSpecsSequentially(suite.specs, options, reason)
+	result.Children = runChildrenSequentially(suite.children, options.skipping(reason))
+	return result.CalculateResults()
 }
 func (suite *SequentialSuite) Run() Result {
+	return suite.RunWithOptions(RunOptions{})
+}
+func (suite *SequentialSuite) RunWithOptions(options RunOptions) Result {
+	options = options.enter(suite)
+	start := time.Now()
+	result := suite.run(options)
+	result.StartedAt = start
+	result.Duration = time.Since(start)
+	options.emitSuiteFinished(result)
+	return result
+}
+func (suite *SequentialSuite) run(options RunOptions) Result {
+	result := options.newResult()
+	if skip, reason := options.skipReason(suite); skip {
+		fmt.Printf("SKIP Sequential Suite: %s\n", suite.name)
+		return suite.skip(result, options, reason)
+	}
 	fmt.Printf("RUN Sequential Suite: %s\n", suite.name)
-	suite.processStep = createProcessStepFn(suite.instance)
-	suite.assert = createAssertFn(suite.instance)
-	err := suite.processStep(suite.beforeAll)
+	options.emitSuiteStarted()
+	injectParameters(suite.instance, options.Parameters)
+	processStep := createProcessStepFn(suite.instance)
+	assert := createAssertFn(suite.instance)
+	cleanups := installCleanupRegistry(suite.instance)
+	err := processStep(suite.beforeAll)
 	if err == nil {
-		suite.result.SpecResults = runSpecsSequentially(suite.specs, suite.instance, suite.beforeEach, suite.assert, suite.afterEach)
-		suite.result.Children = runChildrenSequentially(suite.children)
-		err = suite.processStep(suite.afterAll)
+		result.SpecResults = runSpecsSequentially(suite.specs, suite.instance, suite.beforeEach, assert, suite.afterEach, cleanups, options)
+		result.Children = runChildrenSequentially(suite.children, options)
+		err = processStep(suite.afterAll)
 		if err != nil {
-			suite.result.AfterAllException = &ActionException{
-				Name:    suite.afterAll.Description,
-				Message: err.Error(),
-			}
+			result.AfterAllException = newActionException(suite.afterAll, err)
 		}
 	} else {
-		suite.result.BeforeAllException = &ActionException{
-			Name:    suite.beforeAll.Description,
-			Message: err.Error(),
-		}
-		return suite.Skip()
+		result.BeforeAllException = newActionException(suite.beforeAll, err)
+		result.CleanupExceptions = cleanups.close(suite.instance)
+		return suite.skip(result, options, "")
 	}
-	result := suite.result.CalculateResults()
+	result.CleanupExceptions = cleanups.close(suite.instance)
+	result = result.CalculateResults()
 	fmt.Printf("RESULTS for Suite '%s': passed: %d, skipped: %d, failed: %d\n", suite.GetName(), result.TotalPassed, result.TotalSkipped, result.TotalFailed)
 	return result
 }
 func (suite *SequentialSuite) BeforeEach(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.beforeEach = &Action{Description: description, Do: action}
+	suite.beforeEach = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: BeforeEachHook, Action: *suite.beforeEach})
 	return suite
 }
 func (suite *SequentialSuite) BeforeAll(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.beforeAll = &Action{Description: description, Do: action}
+	suite.beforeAll = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: BeforeAllHook, Action: *suite.beforeAll})
 	return suite
 }
 func (suite *SequentialSuite) AfterEach(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.afterEach = &Action{Description: description, Do: action}
+	suite.afterEach = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: AfterEachHook, Action: *suite.afterEach})
 	return suite
 }
 func (suite *SequentialSuite) AfterAll(description string, action func(instance map[string]interface{}) error) Suite {
-	suite.afterAll = &Action{Description: description, Do: action}
+	suite.afterAll = &Action{Description: description, Do: action, Location: callerLocation()}
+	suite.hooks = append(suite.hooks, Hook{Kind: AfterAllHook, Action: *suite.afterAll})
 	return suite
 }
 func (suite *SequentialSuite) It(description string, assertion func(instance map[string]interface{}) error) Suite {
-	suite.specs = append(suite.specs, Spec{Description: description, It: It{Do: assertion}})
+	suite.specs = append(suite.specs, Spec{Description: description, It: It{Do: assertion}, Location: callerLocation()})
 	return suite
 }
 func (suite *SequentialSuite) XIt(description string, assertion func(instance map[string]interface{}) error) Suite {
-	suite.specs = append(suite.specs, Spec{Description: description, Skip: true, It: It{Do: assertion}})
+	suite.specs = append(suite.specs, Spec{Description: description, Skip: true, It: It{Do: assertion}, Location: callerLocation()})
+	return suite
+}
+
+// FIt declares a spec marked as focused in the Plan. It runs like one declared
+// with It.
+func (suite *SequentialSuite) FIt(description string, assertion func(instance map[string]interface{}) error) *SequentialSuite {
+	suite.specs = append(suite.specs, Spec{Description: description, Focus: true, It: It{Do: assertion}, Location: callerLocation()})
 	return suite
 }
 func (suite *SequentialSuite) Describe(children Suite) Suite {
-	suite.children = append(suite.children, Describe{Suite: children})
+	suite.children = append(suite.children, Describe{Suite: children, Location: callerLocation()})
 	return suite
 }
 func (suite *SequentialSuite) XDescribe(children Suite) Suite {
-	suite.children = append(suite.children, Describe{Skip: true, Suite: children})
+	suite.children = append(suite.children, Describe{Skip: true, Suite: children, Location: callerLocation()})
+	return suite
+}
+
+// FDescribe declares a child suite marked as focused in the Plan. It runs like
+// one declared with Describe.
+func (suite *SequentialSuite) FDescribe(children Suite) *SequentialSuite {
+	suite.children = append(suite.children, Describe{Focus: true, Suite: children, Location: callerLocation()})
 	return suite
 }
 
-func runChildrenSequentially(children []Describe) []Result {
+func runChildrenSequentially(children []Describe, options RunOptions) []Result {
 	results := make([]Result, 0)
 	for _, child := range children {
-		results = append(results, runChild(child))
+		results = append(results, runChild(child, options))
 	}
 	return results
 }
-func runSpecsSequentially(specs []Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action) []SpecResult {
+func runSpecsSequentially(specs []Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action, cleanups *cleanupRegistry, options RunOptions) []SpecResult {
 	results := make([]SpecResult, 0)
 	for _, spec := range specs {
-		if !spec.Skip {
-			results = append(results, runSpec(spec, instance, beforeEach, assert, afterEach))
-		} else {
-			fmt.Printf("SKIP Spec: %s\n", spec.Description)
-			results = append(results, SpecResult{
-				Name:                spec.Description,
-				Status:              "SKIPPED",
-				BeforeEachException: nil,
-				AfterEachException:  nil,
-			})
-		}
+		results = append(results, runSpec(spec, instance, beforeEach, assert, afterEach, cleanups, options))
 	}
 	return results
 }
-func skipSpecsSequentially(specs []Spec) []SpecResult {
+func skipSpecsSequentially(specs []Spec, options RunOptions, reason string) []SpecResult {
 	results := make([]SpecResult, 0)
 	for _, spec := range specs {
-		results = append(results, skipSpec(spec))
-	}
-	return results
-}
-func skipChildrenSequentially(children []Describe) []Result {
-	results := make([]Result, 0)
-	for _, child := range children {
-		results = append(results, skipChild(child))
+		results = append(results, skipSpec(spec, options, reason))
 	}
 	return results
 }
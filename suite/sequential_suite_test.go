@@ -1,7 +1,10 @@
 package suite
 
 import (
+	"encoding/json"
 	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -167,4 +170,255 @@ func TestSequentialSuiteWithAfterAll(t *testing.T) {
 		}
 		return nil
 	}).Run()
-}
\ No newline at end of file
+}
+func TestSequentialSuiteFailFastSkipsRemainingSpecs(t *testing.T) {
+	afterAllRan := false
+	ran := 0
+	result := RunWithOptions(NewSequentialSuite("parent suite").
+		It("1: should fail", func(instance map[string]interface{}) error {
+			ran += 1
+			return fmt.Errorf("exit 1")
+		}).
+		It("2: should not run after first failure", func(instance map[string]interface{}) error {
+			ran += 1
+			return nil
+		}).
+		Describe(NewSequentialSuite("first child suite").
+			It("should not run after first failure", func(instance map[string]interface{}) error {
+				ran += 1
+				return nil
+			})).
+		AfterAll("should still clean up", func(instance map[string]interface{}) error {
+			afterAllRan = true
+			return nil
+		}), RunOptions{FailFast: true})
+
+	if ran != 1 {
+		t.Errorf("expected 1 spec to run but %d ran", ran)
+	}
+	if !afterAllRan {
+		t.Errorf("expected after all to run after abort")
+	}
+	if result.TotalFailed != 1 {
+		t.Errorf("expected 1 total failed but got %d", result.TotalFailed)
+	}
+	if result.TotalSkipped != 2 {
+		t.Errorf("expected 2 total skipped but got %d", result.TotalSkipped)
+	}
+	if result.SpecResults[1].Message != "aborted after 1 failure" {
+		t.Errorf("expected abort reason but got '%s'", result.SpecResults[1].Message)
+	}
+	if result.Children[0].SpecResults[0].Message != "aborted after 1 failure" {
+		t.Errorf("expected abort reason in child but got '%s'", result.Children[0].SpecResults[0].Message)
+	}
+}
+func TestSequentialSuiteMaxFailuresCountsAcrossChildren(t *testing.T) {
+	result := RunWithOptions(NewSequentialSuite("parent suite").
+		It("1: should fail", func(instance map[string]interface{}) error {
+			return fmt.Errorf("exit 1")
+		}).
+		Describe(NewSequentialSuite("first child suite").
+			It("1: should fail", func(instance map[string]interface{}) error {
+				return fmt.Errorf("exit 1")
+			}).
+			It("2: should not run", func(instance map[string]interface{}) error {
+				return nil
+			})), RunOptions{MaxFailures: 2})
+
+	if result.TotalFailed != 2 {
+		t.Errorf("expected 2 total failed but got %d", result.TotalFailed)
+	}
+	if result.TotalSkipped != 1 {
+		t.Errorf("expected 1 total skipped but got %d", result.TotalSkipped)
+	}
+	if result.Children[0].SpecResults[1].Message != "aborted after 2 failures" {
+		t.Errorf("expected abort reason but got '%s'", result.Children[0].SpecResults[1].Message)
+	}
+}
+func TestSequentialSuiteRunsCleanupAfterFailedBeforeAll(t *testing.T) {
+	cleaned := make([]string, 0)
+	result := NewSequentialSuite("parent suite").
+		BeforeAll("create resources then fail", func(instance map[string]interface{}) error {
+			DeferCleanup(instance, "delete account", func(instance map[string]interface{}) error {
+				cleaned = append(cleaned, "account")
+				return nil
+			})
+			DeferCleanup(instance, "delete bucket", func(instance map[string]interface{}) error {
+				cleaned = append(cleaned, "bucket")
+				return fmt.Errorf("bucket not empty")
+			})
+			return fmt.Errorf("exit 1")
+		}).
+		It("should be skipped", func(instance map[string]interface{}) error {
+			return nil
+		}).Run()
+
+	if len(cleaned) != 2 || cleaned[0] != "bucket" || cleaned[1] != "account" {
+		t.Errorf("expected cleanups to run in reverse order but got %v", cleaned)
+	}
+	if len(result.CleanupExceptions) != 1 || result.CleanupExceptions[0].Name != "delete bucket" {
+		t.Errorf("expected failed cleanup to be reported but got %v", result.CleanupExceptions)
+	}
+	if result.TotalSkipped != 1 {
+		t.Errorf("expected 1 total skipped but got %d", result.TotalSkipped)
+	}
+}
+func TestSequentialSuiteRunsSpecCleanupAfterAfterEach(t *testing.T) {
+	steps := make([]string, 0)
+	result := NewSequentialSuite("parent suite").
+		BeforeEach("register cleanup", func(instance map[string]interface{}) error {
+			DeferCleanup(instance, "spec cleanup", func(instance map[string]interface{}) error {
+				steps = append(steps, "cleanup")
+				return fmt.Errorf("cleanup failed")
+			})
+			return nil
+		}).
+		It("should run", func(instance map[string]interface{}) error {
+			steps = append(steps, "it")
+			return nil
+		}).
+		AfterEach("after each", func(instance map[string]interface{}) error {
+			steps = append(steps, "after each")
+			return nil
+		}).
+		AfterAll("after all", func(instance map[string]interface{}) error {
+			steps = append(steps, "after all")
+			return nil
+		}).Run()
+
+	expected := []string{"it", "after each", "cleanup", "after all"}
+	if fmt.Sprint(steps) != fmt.Sprint(expected) {
+		t.Errorf("expected steps %v but got %v", expected, steps)
+	}
+	if len(result.SpecResults[0].CleanupExceptions) != 1 {
+		t.Errorf("expected spec cleanup failure to be reported on the spec result")
+	}
+}
+func TestSequentialSuiteReportsPathsAndIDs(t *testing.T) {
+	result := NewSequentialSuite("parent suite").
+		Describe(NewSequentialSuite("first child suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			})).
+		Describe(NewSequentialSuite("second child suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				return nil
+			})).Run()
+
+	first := result.Children[0].SpecResults[0]
+	second := result.Children[1].SpecResults[0]
+	if first.Path != "parent suite > first child suite > returns 200" {
+		t.Errorf("expected full path but got '%s'", first.Path)
+	}
+	if first.ID == second.ID {
+		t.Errorf("expected specs in different suites to have different ids")
+	}
+	if first.ID != PathID([]string{"parent suite", "first child suite", "returns 200"}) {
+		t.Errorf("expected id to be derived from path but got '%s'", first.ID)
+	}
+	if result.Children[1].Path != "parent suite > second child suite" {
+		t.Errorf("expected suite path but got '%s'", result.Children[1].Path)
+	}
+}
+func TestSequentialSuiteRunsSelectedSpecsOnly(t *testing.T) {
+	secondChildBeforeAllRan := false
+	ran := make([]string, 0)
+	s := NewSequentialSuite("parent suite").
+		It("top level test", func(instance map[string]interface{}) error {
+			ran = append(ran, "top level test")
+			return nil
+		}).
+		Describe(NewSequentialSuite("first child suite").
+			It("returns 200", func(instance map[string]interface{}) error {
+				ran = append(ran, "first child")
+				return nil
+			})).
+		Describe(NewSequentialSuite("second child suite").
+			BeforeAll("should not run", func(instance map[string]interface{}) error {
+				secondChildBeforeAllRan = true
+				return nil
+			}).
+			It("returns 200", func(instance map[string]interface{}) error {
+				ran = append(ran, "second child")
+				return nil
+			}))
+	result := RunWithOptions(s, RunOptions{Select: []string{PathID([]string{"parent suite", "first child suite", "returns 200"})}})
+
+	if len(ran) != 1 || ran[0] != "first child" {
+		t.Errorf("expected only the selected spec to run but got %v", ran)
+	}
+	if secondChildBeforeAllRan {
+		t.Errorf("expected hooks of unselected suites not to run")
+	}
+	if result.TotalPassed != 1 || result.TotalSkipped != 2 {
+		t.Errorf("expected 1 passed and 2 skipped but got %d and %d", result.TotalPassed, result.TotalSkipped)
+	}
+	if result.SpecResults[0].Message != "not selected" {
+		t.Errorf("expected unselected spec to be reported as not selected but got '%s'", result.SpecResults[0].Message)
+	}
+
+	ran = make([]string, 0)
+	RunWithOptions(s, RunOptions{Select: []string{"parent suite > second child suite"}})
+	if len(ran) != 1 || ran[0] != "second child" {
+		t.Errorf("expected selecting a suite to run its specs but got %v", ran)
+	}
+}
+func TestSequentialSuiteReportsSourceLocations(t *testing.T) {
+	var errorLine int
+	result := NewSequentialSuite("parent suite").
+		BeforeEach("fails with location", func(instance map[string]interface{}) error {
+			return fmt.Errorf("exit 1")
+		}).
+		It("should fail with location", func(instance map[string]interface{}) error {
+			return nil
+		}).
+		Describe(NewSequentialSuite("first child suite").
+			It("should fail with location", func(instance map[string]interface{}) error {
+				_, _, errorLine, _ = runtime.Caller(0)
+				return Errorf("exit %d", 1)
+			})).Run()
+
+	specResult := result.Children[0].SpecResults[0]
+	if !strings.HasSuffix(specResult.Location.File, "sequential_suite_test.go") || specResult.Location.Line == 0 {
+		t.Errorf("expected spec location in this file but got %s", specResult.Location)
+	}
+	if specResult.FailureLocation == nil || specResult.FailureLocation.Line != errorLine+1 {
+		t.Errorf("expected failure location at line %d but got %v", errorLine+1, specResult.FailureLocation)
+	}
+	if result.Children[0].Location == nil || result.Children[0].Location.Line == 0 {
+		t.Errorf("expected child suite location but got %v", result.Children[0].Location)
+	}
+	exception := result.SpecResults[0].BeforeEachException
+	if exception == nil || exception.Location.Line == 0 || exception.FailureLocation != nil {
+		t.Errorf("expected hook location without failure location but got %v", exception)
+	}
+	j, _ := json.Marshal(exception)
+	if !strings.Contains(string(j), `"name":"fails with location"`) || !strings.Contains(string(j), `"location":{"file":`) || strings.Contains(string(j), "Location") {
+		t.Errorf("expected snake case location keys but got %s", j)
+	}
+}
+func TestSequentialSuiteRunsUnfocusedSpecsToo(t *testing.T) {
+	ran := make([]string, 0)
+	s := NewSequentialSuite("parent suite")
+	s.It("top level test", func(instance map[string]interface{}) error {
+		ran = append(ran, "top level test")
+		return nil
+	})
+	s.FIt("focused top level test", func(instance map[string]interface{}) error {
+		ran = append(ran, "focused top level test")
+		return nil
+	})
+	s.FDescribe(NewSequentialSuite("first child suite").
+		It("returns 200", func(instance map[string]interface{}) error {
+			ran = append(ran, "first child")
+			return nil
+		}))
+	result := s.Run()
+
+	if fmt.Sprint(ran) != fmt.Sprint([]string{"top level test", "focused top level test", "first child"}) {
+		t.Errorf("expected focus not to restrict the run but got %v", ran)
+	}
+	if result.TotalPassed != 3 {
+		t.Errorf("expected 3 passed but got %d", result.TotalPassed)
+	}
+}
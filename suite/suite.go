@@ -2,11 +2,14 @@ package suite
 
 import (
 	"fmt"
+	"time"
 )
 
 type Describe struct {
-	Skip  bool
-	Suite Suite
+	Skip     bool
+	Focus    bool
+	Suite    Suite
+	Location Location
 }
 type It struct {
 	Skip bool
@@ -15,35 +18,63 @@ type It struct {
 type Action struct {
 	Description string
 	Do          func(instance map[string]interface{}) error
+	Location    Location
+}
+type Hook struct {
+	Kind   string
+	Action Action
 }
 type Spec struct {
 	Skip        bool
+	Focus       bool
 	Description string
 	It          It
+	Location    Location
 }
+
+// ActionException is an error returned by a hook or cleanup. FailureLocation
+// is only set for errors created with Errorf; errors from fmt.Errorf or other
+// packages do not carry a location.
 type ActionException struct {
-	Name    string
-	Message string
+	Name            string    `json:"name"`
+	Message         string    `json:"message"`
+	Location        Location  `json:"location"`
+	FailureLocation *Location `json:"failure_location,omitempty"`
 }
 type SpecResult struct {
-	Name                string           `json:"name"`
-	Status              string           `json:"status"`
-	Message             string           `json:"message"`
-	BeforeEachException *ActionException `json:"before_each_exception"`
-	AfterEachException  *ActionException `json:"after_each_exception"`
+	Name                string            `json:"name"`
+	Path                string            `json:"path"`
+	ID                  string            `json:"id"`
+	Status              string            `json:"status"`
+	Message             string            `json:"message"`
+	Location            Location          `json:"location"`
+	FailureLocation     *Location         `json:"failure_location,omitempty"`
+	Duration            time.Duration     `json:"duration"`
+	BeforeEachException *ActionException  `json:"before_each_exception"`
+	AfterEachException  *ActionException  `json:"after_each_exception"`
+	CleanupExceptions   []ActionException `json:"cleanup_exceptions"`
 }
 type Result struct {
-	Name               string           `json:"name"`
-	BeforeAllException *ActionException `json:"before_all_exception"`
-	SpecResults        []SpecResult     `json:"spec_results"`
-	Children           []Result         `json:"children"`
-	AfterAllException  *ActionException `json:"after_all_exception"`
-	Passed             int              `json:"passed"`
-	Skipped            int              `json:"skipped"`
-	Failed             int              `json:"failed"`
-	TotalPassed        int              `json:"total_passed"`
-	TotalSkipped       int              `json:"total_skipped"`
-	TotalFailed        int              `json:"total_failed"`
+	Name               string            `json:"name"`
+	Path               string            `json:"path"`
+	ID                 string            `json:"id"`
+	Location           *Location         `json:"location"`
+	StartedAt          time.Time         `json:"started_at"`
+	Duration           time.Duration     `json:"duration"`
+	BeforeAllException *ActionException  `json:"before_all_exception"`
+	SpecResults        []SpecResult      `json:"spec_results"`
+	Children           []Result          `json:"children"`
+	AfterAllException  *ActionException  `json:"after_all_exception"`
+	CleanupExceptions  []ActionException `json:"cleanup_exceptions"`
+	Passed             int               `json:"passed"`
+	Skipped            int               `json:"skipped"`
+	Failed             int               `json:"failed"`
+	TotalPassed        int               `json:"total_passed"`
+	TotalSkipped       int               `json:"total_skipped"`
+	TotalFailed        int               `json:"total_failed"`
+	// Problems are set on the root result of a run that could not start,
+	// such as one missing required parameters.
+	Problems []Problem `json:"problems,omitempty"`
 }
 type Suite interface {
 	Run() Result
@@ -59,6 +90,75 @@ type Suite interface {
 	XDescribe(children Suite) Suite
 }
 
+// Runner is implemented by suites that run with RunOptions, like
+// SequentialSuite and ConcurrentSuite.
+type Runner interface {
+	RunWithOptions(options RunOptions) Result
+}
+
+// Inspectable is implemented by suites whose declarations can be read without
+// running them, like SequentialSuite and ConcurrentSuite.
+type Inspectable interface {
+	GetSpecs() []Spec
+	GetChildren() []Describe
+	GetHooks() []Hook
+	GetTags() []string
+	GetParameters() []Parameter
+}
+
+// RunWithOptions runs suite with options if it is a Runner, and with Run,
+// ignoring the options, otherwise.
+func RunWithOptions(suite Suite, options RunOptions) Result {
+	if runner, ok := suite.(Runner); ok {
+		return runner.RunWithOptions(options)
+	}
+	return suite.Run()
+}
+
+// GetSpecs returns the specs declared directly in suite, or none if it is not
+// Inspectable.
+func GetSpecs(suite Suite) []Spec {
+	if inspectable, ok := suite.(Inspectable); ok {
+		return inspectable.GetSpecs()
+	}
+	return nil
+}
+
+// GetChildren returns the child suites declared directly in suite, or none if
+// it is not Inspectable.
+func GetChildren(suite Suite) []Describe {
+	if inspectable, ok := suite.(Inspectable); ok {
+		return inspectable.GetChildren()
+	}
+	return nil
+}
+
+// GetHooks returns the hooks registered on suite, or none if it is not
+// Inspectable.
+func GetHooks(suite Suite) []Hook {
+	if inspectable, ok := suite.(Inspectable); ok {
+		return inspectable.GetHooks()
+	}
+	return nil
+}
+
+// GetTags returns the tags of suite, or none if it is not Inspectable.
+func GetTags(suite Suite) []string {
+	if inspectable, ok := suite.(Inspectable); ok {
+		return inspectable.GetTags()
+	}
+	return nil
+}
+
+// declaredParameters returns the parameters declared directly in suite, or
+// none if it is not Inspectable.
+func declaredParameters(suite Suite) []Parameter {
+	if inspectable, ok := suite.(Inspectable); ok {
+		return inspectable.GetParameters()
+	}
+	return nil
+}
+
 func createProcessStepFn(instance map[string]interface{}) func(action *Action) error {
 	return func(action *Action) error {
 		if action != nil {
@@ -77,6 +177,8 @@ func createAssertFn(instance map[string]interface{}) func(spec *Spec) SpecResult
 				Name:                spec.Description,
 				Status:              "FAILED",
 				Message:             err.Error(),
+				Location:            spec.Location,
+				FailureLocation:     failureLocation(err),
 				BeforeEachException: nil,
 				AfterEachException:  nil,
 			}
@@ -84,46 +186,90 @@ func createAssertFn(instance map[string]interface{}) func(spec *Spec) SpecResult
 			return SpecResult{
 				Name:                spec.Description,
 				Status:              "PASSED",
+				Location:            spec.Location,
 				BeforeEachException: nil,
 				AfterEachException:  nil,
 			}
 		}
 	}
 }
-func skipChild(child Describe) Result {
-	child.Skip = true
-	return child.Suite.Skip()
-}
-func skipSpec(spec Spec) SpecResult {
+func skipSpec(spec Spec, options RunOptions, reason string) SpecResult {
 	fmt.Printf("SKIP Spec: %s\n", spec.Description)
-	return SpecResult{
+	path := appendPath(options.path, spec.Description)
+	specResult := SpecResult{
 		Name:                spec.Description,
+		Path:                JoinPath(path),
+		ID:                  PathID(path),
 		Status:              "SKIPPED",
+		Message:             reason,
+		Location:            spec.Location,
 		BeforeEachException: nil,
 		AfterEachException:  nil,
 	}
+	options.emitSpec(specResult)
+	return specResult
 }
-func runChild(child Describe) Result {
+func runChild(child Describe, options RunOptions) Result {
 	if child.Skip {
 		fmt.Printf("SKIP Suite: %s\n", child.Suite.GetName())
-		return child.Suite.Skip()
+		return locateResult(RunWithOptions(child.Suite, options.skipping("")), child)
 	} else {
-		return child.Suite.Run()
+		return locateResult(RunWithOptions(child.Suite, options), child)
+	}
+}
+func locateResult(result Result, child Describe) Result {
+	location := child.Location
+	result.Location = &location
+	return result
+}
+func newActionException(action *Action, err error) *ActionException {
+	return &ActionException{
+		Name:            action.Description,
+		Message:         err.Error(),
+		Location:        action.Location,
+		FailureLocation: failureLocation(err),
+	}
+}
+
+// runSpec runs spec unless it is skipped. A non-nil cleanups gives the spec
+// its own cleanup scope, which ends after AfterEach.
+func runSpec(spec Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action, cleanups *cleanupRegistry, options RunOptions) SpecResult {
+	if spec.Skip {
+		return skipSpec(spec, options, "")
+	}
+	if reason := options.stopReason(); reason != "" {
+		return skipSpec(spec, options, reason)
+	}
+	path := appendPath(options.path, spec.Description)
+	if !options.selects(path) {
+		return skipSpec(spec, options, "not selected")
 	}
+	start := time.Now()
+	if cleanups != nil {
+		cleanups.push()
+	}
+	specResult := runSpecSteps(spec, instance, beforeEach, assert, afterEach)
+	if cleanups != nil {
+		specResult.CleanupExceptions = cleanups.pop(instance)
+	}
+	specResult.Duration = time.Since(start)
+	specResult.Path = JoinPath(path)
+	specResult.ID = PathID(path)
+	options.recordResult(specResult)
+	options.emitSpec(specResult)
+	return specResult
 }
-func runSpec(spec Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action) SpecResult {
+func runSpecSteps(spec Spec, instance map[string]interface{}, beforeEach *Action, assert func(spec *Spec) SpecResult, afterEach *Action) SpecResult {
 	var err error
 	if beforeEach != nil {
 		err = beforeEach.Do(instance)
 		if err != nil {
 			return SpecResult{
-				Name:   spec.Description,
-				Status: "SKIPPED",
-				BeforeEachException: &ActionException{
-					Name:   beforeEach.Description,
-					Message: err.Error(),
-				},
-				AfterEachException: nil,
+				Name:                spec.Description,
+				Status:              "SKIPPED",
+				Location:            spec.Location,
+				BeforeEachException: newActionException(beforeEach, err),
+				AfterEachException:  nil,
 			}
 		}
 	}
@@ -131,10 +277,7 @@ func runSpec(spec Spec, instance map[string]interface{}, beforeEach *Action, ass
 	if afterEach != nil {
 		err = afterEach.Do(instance)
 		if err != nil {
-			specResult.AfterEachException = &ActionException{
-				Name:    afterEach.Description,
-				Message: err.Error(),
-			}
+			specResult.AfterEachException = newActionException(afterEach, err)
 		}
 	}
 	return specResult
@@ -171,3 +314,22 @@ func (result *Result) CalculateResults() Result {
 	}
 	return *result
 }
+
+// Succeeded reports whether the run had no problems, no spec failed and no
+// hook or cleanup raised an exception anywhere in the result.
+func (result Result) Succeeded() bool {
+	if result.TotalFailed > 0 || len(result.Problems) > 0 || result.BeforeAllException != nil || result.AfterAllException != nil || len(result.CleanupExceptions) > 0 {
+		return false
+	}
+	for _, specResult := range result.SpecResults {
+		if specResult.BeforeEachException != nil || specResult.AfterEachException != nil || len(specResult.CleanupExceptions) > 0 {
+			return false
+		}
+	}
+	for _, child := range result.Children {
+		if !child.Succeeded() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package suite
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	BeforeAllHook  = "BeforeAll"
+	BeforeEachHook = "BeforeEach"
+	AfterEachHook  = "AfterEach"
+	AfterAllHook   = "AfterAll"
+	CleanupHook    = "Cleanup"
+)
+
+const (
+	DuplicateSpecProblem    = "duplicate_spec"
+	DuplicateSuiteProblem   = "duplicate_suite"
+	OverwrittenHookProblem  = "overwritten_hook"
+	EmptySuiteProblem       = "empty_suite"
+	InvalidParameterProblem = "invalid_parameter"
+	MissingParameterProblem = "missing_parameter"
+)
+
+// Problem is a mistake in the construction of a suite tree found by Validate.
+type Problem struct {
+	Kind     string    `json:"kind"`
+	Path     string    `json:"path"`
+	Message  string    `json:"message"`
+	Location *Location `json:"location"`
+}
+
+func (problem Problem) String() string {
+	if problem.Location != nil {
+		return fmt.Sprintf("%s: %s (%s)", problem.Location, problem.Message, problem.Kind)
+	}
+	return fmt.Sprintf("%s (%s)", problem.Message, problem.Kind)
+}
+
+// ValidationError is returned instead of serving or running a suite tree that
+// has problems.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, 0)
+	for _, problem := range err.Problems {
+		messages = append(messages, problem.String())
+	}
+	return fmt.Sprintf("invalid suites: %s", strings.Join(messages, "; "))
+}
+
+// Validate walks the suite tree without running it and returns every problem
+// found: duplicate spec or child suite names within one suite, hooks that were
+// registered twice and silently overwritten, suites without specs or
+// children, and parameters of an unknown kind, with a default of the wrong
+// kind or declared again with another kind.
+func Validate(suite Suite) []Problem {
+	problems := validateSuite(suite, []string{suite.GetName()}, nil)
+	return append(problems, validateParameters(suite, []string{suite.GetName()}, make(map[string]Parameter))...)
+}
+
+func validateSuite(suite Suite, path []string, location *Location) []Problem {
+	problems := make([]Problem, 0)
+	if len(GetSpecs(suite)) == 0 && len(GetChildren(suite)) == 0 {
+		problems = append(problems, Problem{
+			Kind:     EmptySuiteProblem,
+			Path:     JoinPath(path),
+			Message:  fmt.Sprintf("suite '%s' has no specs and no children", JoinPath(path)),
+			Location: location,
+		})
+	}
+	registered := make(map[string]bool)
+	for _, hook := range GetHooks(suite) {
+		if registered[hook.Kind] {
+			hookLocation := hook.Action.Location
+			problems = append(problems, Problem{
+				Kind:     OverwrittenHookProblem,
+				Path:     JoinPath(path),
+				Message:  fmt.Sprintf("%s '%s' overwrites an earlier %s of suite '%s'", hook.Kind, hook.Action.Description, hook.Kind, JoinPath(path)),
+				Location: &hookLocation,
+			})
+		}
+		registered[hook.Kind] = true
+	}
+	descriptions := make(map[string]bool)
+	for _, spec := range GetSpecs(suite) {
+		specPath := appendPath(path, spec.Description)
+		if descriptions[spec.Description] {
+			specLocation := spec.Location
+			problems = append(problems, Problem{
+				Kind:     DuplicateSpecProblem,
+				Path:     JoinPath(specPath),
+				Message:  fmt.Sprintf("spec '%s' is declared more than once", JoinPath(specPath)),
+				Location: &specLocation,
+			})
+		}
+		descriptions[spec.Description] = true
+	}
+	names := make(map[string]bool)
+	for _, child := range GetChildren(suite) {
+		childPath := appendPath(path, child.Suite.GetName())
+		childLocation := child.Location
+		if names[child.Suite.GetName()] {
+			problems = append(problems, Problem{
+				Kind:     DuplicateSuiteProblem,
+				Path:     JoinPath(childPath),
+				Message:  fmt.Sprintf("suite '%s' is declared more than once", JoinPath(childPath)),
+				Location: &childLocation,
+			})
+		}
+		names[child.Suite.GetName()] = true
+		problems = append(problems, validateSuite(child.Suite, childPath, &childLocation)...)
+	}
+	return problems
+}
+
+func validateParameters(suite Suite, path []string, declared map[string]Parameter) []Problem {
+	problems := make([]Problem, 0)
+	for _, parameter := range declaredParameters(suite) {
+		parameterLocation := parameter.Location
+		message := ""
+		if !parameterKinds[parameter.Kind] {
+			message = fmt.Sprintf("has unknown kind '%s'", parameter.Kind)
+		} else if earlier, ok := declared[parameter.Name]; ok && earlier.Kind != parameter.Kind {
+			message = fmt.Sprintf("was declared as %s at %s", earlier.Kind, earlier.Location)
+		} else if parameter.Default != nil {
+			if _, err := convertParameter(parameter.Kind, parameter.Default); err != nil {
+				message = fmt.Sprintf("has a default that %s", err.Error())
+			}
+		}
+		if message != "" {
+			problems = append(problems, Problem{
+				Kind:     InvalidParameterProblem,
+				Path:     JoinPath(path),
+				Message:  fmt.Sprintf("parameter '%s' of suite '%s' %s", parameter.Name, JoinPath(path), message),
+				Location: &parameterLocation,
+			})
+		}
+		if _, ok := declared[parameter.Name]; !ok {
+			declared[parameter.Name] = parameter
+		}
+	}
+	for _, child := range GetChildren(suite) {
+		problems = append(problems, validateParameters(child.Suite, appendPath(path, child.Suite.GetName()), declared)...)
+	}
+	return problems
+}
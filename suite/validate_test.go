@@ -0,0 +1,50 @@
+package suite
+
+import (
+	"testing"
+)
+
+func TestValidateReportsConstructionMistakes(t *testing.T) {
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	problems := Validate(NewSequentialSuite("parent suite").
+		BeforeEach("first before each", noop).
+		BeforeEach("second before each", noop).
+		It("returns 200", noop).
+		It("returns 200", noop).
+		Describe(NewConcurrentSuite("empty child suite")))
+
+	kinds := make(map[string]int)
+	for _, problem := range problems {
+		kinds[problem.Kind] += 1
+	}
+	if kinds[OverwrittenHookProblem] != 1 {
+		t.Errorf("expected 1 overwritten hook problem but got %d", kinds[OverwrittenHookProblem])
+	}
+	if kinds[DuplicateSpecProblem] != 1 {
+		t.Errorf("expected 1 duplicate spec problem but got %d", kinds[DuplicateSpecProblem])
+	}
+	if kinds[EmptySuiteProblem] != 1 {
+		t.Errorf("expected 1 empty suite problem but got %d", kinds[EmptySuiteProblem])
+	}
+	for _, problem := range problems {
+		if problem.Location == nil {
+			t.Errorf("expected problem '%s' to have a location", problem.Message)
+		}
+	}
+}
+func TestValidateAcceptsWellFormedSuite(t *testing.T) {
+	noop := func(instance map[string]interface{}) error {
+		return nil
+	}
+	problems := Validate(NewSequentialSuite("parent suite").
+		BeforeEach("before each", noop).
+		It("returns 200", noop).
+		Describe(NewSequentialSuite("first child suite").
+			It("returns 200", noop)))
+
+	if len(problems) != 0 {
+		t.Errorf("expected no problems but got %v", problems)
+	}
+}